@@ -6,12 +6,20 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/atharva-navani16/chat-app.git/internal/auth"
 	"github.com/atharva-navani16/chat-app.git/internal/chat"
 	"github.com/atharva-navani16/chat-app.git/internal/config"
+	"github.com/atharva-navani16/chat-app.git/internal/contacts"
 	"github.com/atharva-navani16/chat-app.git/internal/file"
+	"github.com/atharva-navani16/chat-app.git/internal/keys"
 	"github.com/atharva-navani16/chat-app.git/internal/shared/database"
+	"github.com/atharva-navani16/chat-app.git/internal/user"
 	"github.com/gin-gonic/gin"
 )
 
@@ -32,6 +40,12 @@ func main() {
 	gin.SetMode(cfg.GinMode)
 	router := gin.Default()
 
+	// Client IPs drive per-IP rate limits, so only believe X-Forwarded-For
+	// from configured proxies
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Add CORS middleware for development
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
@@ -49,23 +63,49 @@ func main() {
 	// Initialize services
 
 	// Initialize WebSocket hub
-	wsHub := chat.NewWSHub(rdb)
+	wsHub := chat.NewWSHub(rdb, db)
 	go wsHub.Run()
 
-	authService := auth.NewAuthService(db, rdb, cfg)
+	jwtKeys, err := auth.NewKeyManager(cfg)
+	if err != nil {
+		log.Fatal("Failed to load JWT keys:", err)
+	}
+
+	// Reload JWT keys on SIGHUP so signing keys can be rotated without a restart
+	go func() {
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		for range reload {
+			if err := jwtKeys.Reload(); err != nil {
+				log.Printf("❌ Failed to reload JWT keys: %v", err)
+			}
+		}
+	}()
+
+	fileService, err := file.NewFileService(db, cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize file service:", err)
+	}
+
+	smsSender, err := auth.NewSMSSender(cfg)
+	if err != nil {
+		log.Fatal("Failed to configure SMS provider:", err)
+	}
+	authService := auth.NewAuthService(db, rdb, cfg, wsHub, smsSender, jwtKeys)
 	chatService := chat.NewChatService(db, rdb, cfg, wsHub)
+	keyService := keys.NewKeyService(db, rdb)
+	userService := user.NewUserService(db, wsHub, fileService, auth.NewUsernamePolicy(db, cfg))
+	contactService := contacts.NewContactService(db, rdb, wsHub)
 
 	// Initialize handlers
 	authHandler := auth.NewAuthHandler(authService)
 	chatHandler := chat.NewChatHandler(chatService)
+	keyHandler := keys.NewKeyHandler(keyService)
+	contactHandler := contacts.NewContactHandler(contactService)
+	userHandler := user.NewUserHandler(userService)
 
 	// Initialize JWT middleware
-	jwtMiddleware := auth.NewJWTMiddleware(cfg, db)
-
-	fileService, err := file.NewFileService(db, cfg)
-	if err != nil {
-		log.Fatal("Failed to initialize file service:", err)
-	}
+	jwtMiddleware := auth.NewJWTMiddleware(cfg, db, authService)
 
 	// Initialize file handler
 	fileHandler := file.NewFileHandler(fileService)
@@ -74,6 +114,19 @@ func main() {
 	ctx := context.Background()
 	go wsHub.RedisSubscriber(ctx)
 
+	// Keep connection counts alive and clear online flags left behind by
+	// servers that stopped without cleaning up
+	go wsHub.RunPresenceMaintenance(ctx)
+
+	// Anonymise accounts whose deletion grace period has ended and schedule
+	// deletion of accounts inactive for longer than their self-destruct
+	// setting. Inactivity deletion stays a dry run unless explicitly disabled.
+	inactivityDryRun, err := strconv.ParseBool(cfg.AccountTTLDryRun)
+	if err != nil {
+		inactivityDryRun = true
+	}
+	go userService.RunDeletionSweeper(ctx, time.Hour, inactivityDryRun)
+
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		connectionCount := wsHub.GetConnectionCount()
@@ -95,14 +148,40 @@ func main() {
 		})
 	})
 
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	// API routes
 	api := router.Group("/api/v1")
 	{
 		// Public auth routes (no authentication required)
 		authRoutes := api.Group("/auth")
 		{
+			authRoutes.POST("/phone/request-code", authHandler.RequestPhoneCode)
+			authRoutes.POST("/phone/verify", authHandler.VerifyPhoneCode)
 			authRoutes.POST("/register", authHandler.Register)
 			authRoutes.POST("/login", authHandler.Login)
+			authRoutes.POST("/refresh", authHandler.RefreshToken)
+
+			// Session termination (authentication required)
+			authRoutes.POST("/logout", jwtMiddleware.AuthRequired(), authHandler.Logout)
+			authRoutes.POST("/logout-all", jwtMiddleware.AuthRequired(), authHandler.LogoutAll)
+
+			// Two-factor authentication
+			authRoutes.POST("/2fa/verify", authHandler.VerifyTwoFactor)
+			authRoutes.POST("/2fa/setup", jwtMiddleware.AuthRequired(), authHandler.SetupTwoFactor)
+			authRoutes.POST("/2fa/enable", jwtMiddleware.AuthRequired(), authHandler.EnableTwoFactor)
+			authRoutes.POST("/2fa/disable", jwtMiddleware.AuthRequired(), authHandler.DisableTwoFactor)
+			authRoutes.POST("/2fa/recovery-codes", jwtMiddleware.AuthRequired(), authHandler.RegenerateRecoveryCodes)
+
+			// Password reset by SMS code
+			authRoutes.POST("/password/reset/request", authHandler.RequestPasswordReset)
+			authRoutes.POST("/password/reset/verify", authHandler.VerifyPasswordReset)
+			authRoutes.POST("/password/reset/confirm", authHandler.ConfirmPasswordReset)
+
+			// Device sessions (authentication required)
+			authRoutes.GET("/sessions", jwtMiddleware.AuthRequired(), authHandler.ListSessions)
+			authRoutes.DELETE("/sessions/:session_id", jwtMiddleware.AuthRequired(), authHandler.TerminateSession)
 		}
 
 		// Protected user routes (authentication required)
@@ -110,8 +189,41 @@ func main() {
 		userRoutes.Use(jwtMiddleware.AuthRequired())
 		{
 			userRoutes.GET("/me", getUserProfile)
-			userRoutes.PUT("/me", updateUserProfile)
+			userRoutes.PUT("/me", userHandler.UpdateProfile)
+			userRoutes.PUT("/me/password", authHandler.ChangePassword)
+			userRoutes.POST("/me/deletion/code", authHandler.RequestAccountDeletionCode)
+			userRoutes.DELETE("/me", authHandler.DeleteAccount)
+			userRoutes.GET("/me/account-ttl", userHandler.GetAccountTTL)
+			userRoutes.PUT("/me/account-ttl", userHandler.SetAccountTTL)
+			userRoutes.POST("/me/photos", userHandler.UploadProfilePhoto)
+			userRoutes.GET("/me/photos", userHandler.ListProfilePhotos)
+			userRoutes.PUT("/me/photos/:photo_id/current", userHandler.SetCurrentProfilePhoto)
+			userRoutes.DELETE("/me/photos/:photo_id", userHandler.DeleteProfilePhoto)
 			userRoutes.GET("/search", authHandler.SearchUsers) // Search users
+			userRoutes.GET("/:user_id/status", userHandler.GetUserStatus)
+		}
+
+		// Contacts (authentication required)
+		contactRoutes := api.Group("/contacts")
+		contactRoutes.Use(jwtMiddleware.AuthRequired())
+		{
+			contactRoutes.GET("", contactHandler.ListContacts)              // List contacts
+			contactRoutes.POST("", contactHandler.AddContact)               // Add or rename a contact
+			contactRoutes.POST("/import", contactHandler.ImportContacts)    // Import phone book
+			contactRoutes.DELETE("/:user_id", contactHandler.RemoveContact) // Remove a contact
+			contactRoutes.GET("/blocked", contactHandler.ListBlockedUsers)
+			contactRoutes.POST("/blocked", contactHandler.BlockUser)
+			contactRoutes.DELETE("/blocked/:user_id", contactHandler.UnblockUser)
+		}
+
+		// E2E key bundles (authentication required)
+		keyRoutes := api.Group("/keys")
+		keyRoutes.Use(jwtMiddleware.AuthRequired())
+		{
+			keyRoutes.PUT("", keyHandler.UploadKeys)                     // Upload identity key and signed prekey
+			keyRoutes.POST("/one-time", keyHandler.UploadOneTimePrekeys) // Upload one-time prekeys
+			keyRoutes.GET("/count", keyHandler.GetPrekeyCount)           // Remaining one-time prekeys
+			keyRoutes.GET("/:user_id", keyHandler.GetPrekeyBundle)       // Fetch a prekey bundle
 		}
 
 		// Protected chat routes (authentication required)
@@ -168,7 +280,7 @@ func main() {
 		publicRoutes := api.Group("/public")
 		publicRoutes.Use(jwtMiddleware.OptionalAuth())
 		{
-			publicRoutes.GET("/users/:username", getPublicUserProfile)
+			publicRoutes.GET("/users/:username", userHandler.GetPublicProfile)
 		}
 	}
 
@@ -181,21 +293,61 @@ func main() {
 	fmt.Println("📡 ═══════════════════════════════════════════════════")
 	fmt.Println("")
 	fmt.Println("🔐 Authentication:")
+	fmt.Println("   🔓 POST /api/v1/auth/phone/request-code - Send phone verification code")
+	fmt.Println("   🔓 POST /api/v1/auth/phone/verify    - Verify phone code")
 	fmt.Println("   🔓 POST /api/v1/auth/register        - Register new user")
 	fmt.Println("   🔓 POST /api/v1/auth/login           - Login user")
+	fmt.Println("   🔓 POST /api/v1/auth/refresh         - Rotate refresh token")
+	fmt.Println("   🔒 POST /api/v1/auth/logout          - Log out current session")
+	fmt.Println("   🔒 POST /api/v1/auth/logout-all      - Log out all sessions")
+	fmt.Println("   🔓 POST /api/v1/auth/2fa/verify      - Complete login with 2FA code")
+	fmt.Println("   🔒 POST /api/v1/auth/2fa/setup       - Start 2FA enrollment")
+	fmt.Println("   🔒 POST /api/v1/auth/2fa/enable      - Confirm 2FA enrollment")
+	fmt.Println("   🔒 POST /api/v1/auth/2fa/disable     - Disable 2FA")
+	fmt.Println("   🔒 POST /api/v1/auth/2fa/recovery-codes - Regenerate recovery codes")
+	fmt.Println("   🔓 POST /api/v1/auth/password/reset/request - Send password reset code")
+	fmt.Println("   🔓 POST /api/v1/auth/password/reset/verify  - Verify reset code")
+	fmt.Println("   🔓 POST /api/v1/auth/password/reset/confirm - Set new password")
+	fmt.Println("   🔒 GET  /api/v1/auth/sessions        - List active sessions")
+	fmt.Println("   🔒 DEL  /api/v1/auth/sessions/:id    - Terminate a session")
 	fmt.Println("")
 	fmt.Println("👤 User Management:")
 	fmt.Println("   🔒 GET  /api/v1/users/me             - Get current user profile")
 	fmt.Println("   🔒 PUT  /api/v1/users/me             - Update user profile")
+	fmt.Println("   🔒 PUT  /api/v1/users/me/password    - Change password")
+	fmt.Println("   🔒 POST /api/v1/users/me/deletion/code - Request account deletion code")
+	fmt.Println("   🔒 DELETE /api/v1/users/me           - Schedule account deletion")
+	fmt.Println("   🔒 GET  /api/v1/users/me/account-ttl - Get inactivity self-destruct period")
+	fmt.Println("   🔒 PUT  /api/v1/users/me/account-ttl - Set inactivity self-destruct period")
+	fmt.Println("   🔒 POST /api/v1/users/me/photos      - Upload profile photo")
+	fmt.Println("   🔒 GET  /api/v1/users/me/photos      - List profile photos")
+	fmt.Println("   🔒 PUT  /api/v1/users/me/photos/:id/current - Set current profile photo")
+	fmt.Println("   🔒 DELETE /api/v1/users/me/photos/:id - Delete profile photo")
 	fmt.Println("   🔒 GET  /api/v1/users/search?q=name  - Search users")
+	fmt.Println("   🔒 GET  /api/v1/users/:id/status     - Online status and last seen")
+	fmt.Println("")
+	fmt.Println("📇 Contacts:")
+	fmt.Println("   🔒 GET  /api/v1/contacts             - List contacts")
+	fmt.Println("   🔒 POST /api/v1/contacts             - Add or rename a contact")
+	fmt.Println("   🔒 POST /api/v1/contacts/import      - Import phone book contacts")
+	fmt.Println("   🔒 DEL  /api/v1/contacts/:user_id    - Remove a contact")
+	fmt.Println("   🔒 GET  /api/v1/contacts/blocked     - List blocked users")
+	fmt.Println("   🔒 POST /api/v1/contacts/blocked     - Block a user")
+	fmt.Println("   🔒 DEL  /api/v1/contacts/blocked/:user_id - Unblock a user")
+	fmt.Println("")
+	fmt.Println("🗝️ E2E Keys:")
+	fmt.Println("   🔒 PUT  /api/v1/keys                 - Upload identity key and signed prekey")
+	fmt.Println("   🔒 POST /api/v1/keys/one-time        - Upload one-time prekeys")
+	fmt.Println("   🔒 GET  /api/v1/keys/count           - Remaining one-time prekeys")
+	fmt.Println("   🔒 GET  /api/v1/keys/:user_id        - Fetch prekey bundle")
 	fmt.Println("")
 	fmt.Println("💬 Chat Management:")
 	fmt.Println("   🔒 GET  /api/v1/chats                - Get user's chats")
 	fmt.Println("   🔒 POST /api/v1/chats/private        - Create private chat")
 	fmt.Println("   🔒 POST /api/v1/chats/group          - Create group chat")
 	fmt.Println("   🔒 GET  /api/v1/chats/:id            - Get chat details")
-	fmt.Println("   🔒 PUT  /api/v1/chats/:id            - Update chat info")
-	fmt.Println("   🔒 POST /api/v1/chats/:id/leave      - Leave chat")
+	fmt.Println("   🔒 PUT  /api/v1/chats/:id            - Update group or channel info")
+	fmt.Println("   🔒 POST /api/v1/chats/:id/leave      - Leave or delete chat")
 	fmt.Println("")
 	fmt.Println("📨 Messaging:")
 	fmt.Println("   🔒 GET  /api/v1/chats/:id/messages   - Get chat messages")
@@ -205,13 +357,14 @@ func main() {
 	fmt.Println("👥 Member Management:")
 	fmt.Println("   🔒 GET  /api/v1/chats/:id/members    - Get chat members")
 	fmt.Println("   🔒 POST /api/v1/chats/:id/members    - Add members")
-	fmt.Println("   🔒 DEL  /api/v1/chats/:id/members/:user_id - Remove member")
+	fmt.Println("   🔒 DEL  /api/v1/chats/:id/members/:user_id - Remove member (?ban=true to ban)")
 	fmt.Println("")
 	fmt.Println("🔌 Real-time:")
 	fmt.Println("   🔒 WS   /api/v1/ws/connect           - WebSocket connection")
 	fmt.Println("")
 	fmt.Println("🔓 Public:")
 	fmt.Println("   🔓 GET  /api/v1/public/users/:username - Public user profile")
+	fmt.Println("   🔓 GET  /.well-known/jwks.json       - Token verification keys")
 	fmt.Println("   🔓 GET  /health                      - Health check")
 	fmt.Println("")
 	fmt.Println("📡 ═══════════════════════════════════════════════════")
@@ -221,7 +374,26 @@ func main() {
 	fmt.Println("📡 ═══════════════════════════════════════════════════")
 	fmt.Println("")
 
-	router.Run(serverAddr)
+	server := &http.Server{Addr: serverAddr, Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Server failed:", err)
+		}
+	}()
+
+	// Withdraw this server's connections from presence before exiting
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("🛑 Shutting down")
+	wsHub.ReleasePresence()
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("❌ Server shutdown failed: %v", err)
+	}
 }
 
 // User management handlers (examples)
@@ -247,54 +419,3 @@ func getUserProfile(c *gin.Context) {
 		},
 	})
 }
-
-func updateUserProfile(c *gin.Context) {
-	user, exists := auth.RequireUser(c)
-	if !exists {
-		return
-	}
-
-	var updateReq struct {
-		FirstName string `json:"first_name"`
-		LastName  string `json:"last_name"`
-		Bio       string `json:"bio"`
-	}
-
-	if err := c.ShouldBindJSON(&updateReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
-		return
-	}
-
-	// TODO: Implement actual update logic in user service
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Profile updated successfully",
-		"data": gin.H{
-			"user_id": user.Id,
-			"updates": updateReq,
-		},
-	})
-}
-
-func getPublicUserProfile(c *gin.Context) {
-	username := c.Param("username")
-
-	currentUser, isAuthenticated := auth.GetCurrentUser(c)
-
-	// TODO: Implement logic to get public profile from database
-	response := gin.H{
-		"message": "Public profile retrieved",
-		"data": gin.H{
-			"username":    username,
-			"is_public":   true,
-			"bio":         "This is a sample bio",
-			"joined_date": "2024-01-01",
-		},
-		"viewer_authenticated": isAuthenticated,
-	}
-
-	if isAuthenticated {
-		response["viewer"] = currentUser.Username
-	}
-
-	c.JSON(http.StatusOK, response)
-}
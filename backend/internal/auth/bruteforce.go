@@ -0,0 +1,183 @@
+// internal/auth/bruteforce.go
+package auth
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	loginFailureWindow            = 15 * time.Minute
+	maxLoginFailuresPerIdentifier = 5
+	maxLoginFailuresPerIP         = 20
+	loginLockoutBase              = time.Minute // doubles with each lockout
+	loginLockoutMax               = time.Hour
+	lockoutLevelTTL               = 24 * time.Hour
+
+	registrationWindow           = time.Hour
+	maxRegistrationsPerIP        = 10
+	maxRegistrationsPerPhone     = 3
+	loginFailuresKeyPrefix       = "auth:login_failures:"    // + scope:value, sliding window of failures
+	loginLockoutKeyPrefix        = "auth:lockout:"           // + scope:value, set while locked out
+	loginLockoutLevelKeyPrefix   = "auth:lockout_level:"     // + scope:value, lockouts in the last day
+	registrationAttemptKeyPrefix = "auth:register_attempts:" // + scope:value, sliding window of attempts
+
+	credentialAttemptWindow    = 15 * time.Minute
+	maxCredentialAttempts      = 5
+	credentialAttemptKeyPrefix = "auth:credential_attempts:" // + user ID, password and 2FA guesses outside login
+)
+
+// Dummy hash compared against when an account does not exist, so failed
+// logins take the same time whether or not the identifier is registered
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// loginIdentifier normalizes the account identifier a login attempt targets
+func loginIdentifier(req *LoginRequest) string {
+	if req.PhoneNumber != "" {
+		return "phone:" + req.PhoneNumber
+	}
+	return "username:" + strings.ToLower(req.Username)
+}
+
+// twoFactorIdentifier is the lockout scope for second-factor guesses on an
+// account, shared by every identifier the account can log in with
+func twoFactorIdentifier(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+// checkLoginAllowed rejects attempts while the identifier or IP is locked out
+func (s *AuthService) checkLoginAllowed(identifier, ipAddress string) error {
+	ctx := context.Background()
+
+	pipe := s.rdb.Pipeline()
+	identifierTTL := pipe.PTTL(ctx, loginLockoutKeyPrefix+identifier)
+	ipTTL := pipe.PTTL(ctx, loginLockoutKeyPrefix+"ip:"+ipAddress)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	retryAfter := identifierTTL.Val()
+	if ipTTL.Val() > retryAfter {
+		retryAfter = ipTTL.Val()
+	}
+	if retryAfter > 0 {
+		return &RateLimitError{Reason: "too many failed login attempts", RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login against the identifier and IP,
+// locking either out once it exceeds its limit
+func (s *AuthService) recordLoginFailure(identifier, ipAddress string) {
+	s.recordFailure(identifier, maxLoginFailuresPerIdentifier)
+	s.recordFailure("ip:"+ipAddress, maxLoginFailuresPerIP)
+}
+
+// recordFailure adds a failure for one scope and applies a progressive lockout
+func (s *AuthService) recordFailure(scope string, limit int64) {
+	ctx := context.Background()
+
+	failures, err := s.limiter.Record(ctx, loginFailuresKeyPrefix+scope, loginFailureWindow)
+	if err != nil {
+		log.Printf("❌ Failed to record login failure for %s: %v", scope, err)
+		return
+	}
+	if failures < limit {
+		return
+	}
+
+	// Each lockout within a day doubles the next one
+	level, err := s.rdb.Incr(ctx, loginLockoutLevelKeyPrefix+scope).Result()
+	if err != nil {
+		log.Printf("❌ Failed to update lockout level for %s: %v", scope, err)
+		return
+	}
+	s.rdb.Expire(ctx, loginLockoutLevelKeyPrefix+scope, lockoutLevelTTL)
+
+	duration := lockoutDuration(level)
+	s.rdb.Set(ctx, loginLockoutKeyPrefix+scope, level, duration)
+	s.limiter.Reset(ctx, loginFailuresKeyPrefix+scope)
+
+	log.Printf("🔒 Login locked for %s for %s after %d failures", scope, duration, failures)
+}
+
+// resetLoginFailures clears the failure history of an identifier after a successful login
+func (s *AuthService) resetLoginFailures(identifier string) {
+	ctx := context.Background()
+	s.rdb.Del(ctx, loginFailuresKeyPrefix+identifier, loginLockoutLevelKeyPrefix+identifier)
+}
+
+// checkRegistrationAllowed limits how often an IP or phone number may register
+func (s *AuthService) checkRegistrationAllowed(phoneNumber, ipAddress string) error {
+	ctx := context.Background()
+
+	limits := []struct {
+		scope string
+		limit int
+	}{
+		{"ip:" + ipAddress, maxRegistrationsPerIP},
+		{"phone:" + phoneNumber, maxRegistrationsPerPhone},
+	}
+
+	for _, l := range limits {
+		result, err := s.limiter.Allow(ctx, registrationAttemptKeyPrefix+l.scope, l.limit, registrationWindow)
+		if err != nil {
+			return err
+		}
+		if !result.Allowed {
+			return &RateLimitError{Reason: "too many registration attempts", RetryAfter: positiveDuration(result.RetryAfter)}
+		}
+	}
+
+	return nil
+}
+
+// checkCredentialAttempt counts a password or second-factor guess made for
+// an account outside of login (password change, reset, 2FA management) and
+// rejects it once too many were made within the window
+func (s *AuthService) checkCredentialAttempt(userID uuid.UUID) error {
+	ctx := context.Background()
+
+	result, err := s.limiter.Allow(ctx, credentialAttemptKeyPrefix+userID.String(), maxCredentialAttempts, credentialAttemptWindow)
+	if err != nil {
+		return err
+	}
+	if !result.Allowed {
+		return &RateLimitError{Reason: "too many incorrect attempts", RetryAfter: positiveDuration(result.RetryAfter)}
+	}
+	return nil
+}
+
+// resetCredentialAttempts clears an account's guess count after a correct answer
+func (s *AuthService) resetCredentialAttempts(userID uuid.UUID) {
+	s.limiter.Reset(context.Background(), credentialAttemptKeyPrefix+userID.String())
+}
+
+// lockoutDuration returns the lockout length for the nth lockout
+func lockoutDuration(level int64) time.Duration {
+	duration := loginLockoutBase
+	for i := int64(1); i < level && duration < loginLockoutMax; i++ {
+		duration *= 2
+	}
+	if duration > loginLockoutMax {
+		duration = loginLockoutMax
+	}
+	return duration
+}
+
+// simulatePasswordCheck spends the same time as a real bcrypt comparison
+func simulatePasswordCheck(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
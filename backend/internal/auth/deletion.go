@@ -0,0 +1,107 @@
+// internal/auth/deletion.go
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Values of users.status
+const (
+	UserStatusActive      = "active"
+	UserStatusDeactivated = "deactivated" // deletion scheduled, can still sign in to cancel
+	UserStatusDeleted     = "deleted"
+	UserStatusBanned      = "banned"
+)
+
+// AccountDeletionGracePeriod is how long a deactivated account can be
+// recovered by signing in before it is anonymised
+const AccountDeletionGracePeriod = 7 * 24 * time.Hour
+
+var ErrDeletionNotAllowed = errors.New("account cannot be deleted")
+
+// RequestAccountDeletionCode sends a deletion confirmation code to the
+// account's phone number
+func (s *AuthService) RequestAccountDeletionCode(userID uuid.UUID) (time.Time, error) {
+	phoneNumber, err := s.getActivePhoneNumber(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return s.sendCode(otpPurposeDelete, phoneNumber)
+}
+
+// ScheduleAccountDeletion deactivates the account and signs out every
+// session. The account is anonymised once the grace period ends unless the
+// user signs in again before then.
+func (s *AuthService) ScheduleAccountDeletion(userID uuid.UUID, req *DeleteAccountRequest) (*AccountDeletionResponse, error) {
+	phoneNumber, err := s.getActivePhoneNumber(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCode(otpPurposeDelete, phoneNumber, req.Code); err != nil {
+		return nil, err
+	}
+
+	// An SMS code alone must not bypass 2FA
+	enabled, err := s.isTwoFactorEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		ok, err := s.checkSecondFactor(userID, req.TwoFactorCode, req.RecoveryCode)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrSecondFactorRequired
+		}
+	}
+
+	var scheduledAt time.Time
+	query := `
+		UPDATE users
+		SET status = 'deactivated', deletion_scheduled_at = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'active'
+		RETURNING deletion_scheduled_at`
+	err = s.db.QueryRow(query, userID, time.Now().Add(AccountDeletionGracePeriod)).Scan(&scheduledAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDeletionNotAllowed
+		}
+		return nil, err
+	}
+
+	if err := s.LogoutAll(userID); err != nil {
+		return nil, err
+	}
+
+	return &AccountDeletionResponse{DeletionScheduledAt: scheduledAt}, nil
+}
+
+// cancelAccountDeletion reactivates an account that is pending deletion
+func (s *AuthService) cancelAccountDeletion(db execer, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET status = 'active', deletion_scheduled_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'deactivated'`
+	_, err := db.Exec(query, userID)
+	return err
+}
+
+// getActivePhoneNumber returns the phone number of an active account
+func (s *AuthService) getActivePhoneNumber(userID uuid.UUID) (string, error) {
+	var phoneNumber string
+	query := `SELECT phone_number FROM users WHERE id = $1 AND status = 'active'`
+	if err := s.db.QueryRow(query, userID).Scan(&phoneNumber); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrDeletionNotAllowed
+		}
+		return "", err
+	}
+	return phoneNumber, nil
+}
@@ -1,9 +1,15 @@
 package auth
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
@@ -23,9 +29,25 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := h.authService.Register(&req)
+	authResponse, err := h.authService.Register(&req, deviceInfo(c, req.DeviceName))
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		var rateLimitErr *RateLimitError
+		switch {
+		case errors.As(err, &rateLimitErr):
+			respondRateLimited(c, rateLimitErr)
+		case errors.Is(err, ErrInvalidPhoneNumber):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_PHONE_NUMBER"})
+		case errors.Is(err, ErrInvalidVerification):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "PHONE_NOT_VERIFIED"})
+		case errors.Is(err, ErrWeakPassword):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "WEAK_PASSWORD"})
+		case errors.Is(err, ErrAccountExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "ACCOUNT_EXISTS"})
+		case errors.Is(err, ErrUsernameReserved):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "USERNAME_RESERVED"})
+		default:
+			c.JSON(500, gin.H{"error": "Registration failed"})
+		}
 		return
 	}
 
@@ -39,15 +61,573 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := h.authService.Login(&req)
+	authResponse, err := h.authService.Login(&req, deviceInfo(c, req.DeviceName))
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		var twoFactorErr *TwoFactorRequiredError
+		var rateLimitErr *RateLimitError
+		switch {
+		case errors.As(err, &twoFactorErr):
+			respondTwoFactorRequired(c, twoFactorErr)
+		case errors.As(err, &rateLimitErr):
+			respondRateLimited(c, rateLimitErr)
+		case errors.Is(err, ErrInvalidCredentials):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials", "code": "INVALID_CREDENTIALS"})
+		case errors.Is(err, ErrAccountBanned):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "ACCOUNT_BANNED"})
+		case errors.Is(err, ErrMissingCredentials):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "Login failed"})
+		}
 		return
 	}
 
 	c.JSON(200, authResponse)
 }
 
+// RequestPhoneCode sends a verification code to a phone number
+// POST /api/v1/auth/phone/request-code
+func (h *AuthHandler) RequestPhoneCode(c *gin.Context) {
+	var req PhoneCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	expiresAt, err := h.authService.RequestPhoneCode(req.PhoneNumber, req.Purpose)
+	if err != nil {
+		respondCodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Verification code sent",
+		"expires_at": expiresAt,
+	})
+}
+
+// VerifyPhoneCode checks a verification code and returns a verification token
+// POST /api/v1/auth/phone/verify
+func (h *AuthHandler) VerifyPhoneCode(c *gin.Context) {
+	var req PhoneVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	verification, err := h.authService.VerifyPhoneCode(req.PhoneNumber, req.Code)
+	if err != nil {
+		respondCodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Phone number verified",
+		"data":    verification,
+	})
+}
+
+// respondCodeError maps one-time code errors to HTTP responses
+func respondCodeError(c *gin.Context, err error) {
+	var rateLimitErr *RateLimitError
+	switch {
+	case errors.As(err, &rateLimitErr):
+		respondRateLimited(c, rateLimitErr)
+	case errors.Is(err, ErrInvalidPhoneNumber):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_PHONE_NUMBER"})
+	case errors.Is(err, ErrInvalidCodePurpose):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_PURPOSE"})
+	case errors.Is(err, ErrInvalidCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_CODE"})
+	case errors.Is(err, ErrTooManyAttempts):
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Too many incorrect attempts, request a new code",
+			"code":  "TOO_MANY_ATTEMPTS",
+		})
+	case errors.Is(err, ErrSMSDeliveryUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send verification code"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Verification failed"})
+	}
+}
+
+// respondRateLimited writes a 429 with a Retry-After header
+func respondRateLimited(c *gin.Context, err *RateLimitError) {
+	retryAfter := int(math.Ceil(err.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many requests, please try again later",
+		"code":        "RATE_LIMITED",
+		"retry_after": retryAfter,
+	})
+}
+
+// VerifyTwoFactor completes a login with a TOTP or recovery code
+// POST /api/v1/auth/2fa/verify
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code required"})
+		return
+	}
+
+	authResponse, err := h.authService.VerifyTwoFactor(&req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidChallenge) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_CHALLENGE",
+			})
+			return
+		}
+		respondCodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, authResponse)
+}
+
+// SetupTwoFactor starts TOTP enrollment
+// POST /api/v1/auth/2fa/setup
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	user, exists := RequireUser(c)
+	if !exists {
+		return
+	}
+
+	setup, err := h.authService.SetupTwoFactor(user.Id)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Scan the secret with an authenticator app, then confirm with a code",
+		"data":    setup,
+	})
+}
+
+// EnableTwoFactor confirms TOTP enrollment and returns recovery codes
+// POST /api/v1/auth/2fa/enable
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	user, exists := RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req TwoFactorEnableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	codes, err := h.authService.EnableTwoFactor(user.Id, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication enabled",
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// DisableTwoFactor turns off 2FA after re-authentication
+// POST /api/v1/auth/2fa/disable
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	user, exists := RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req TwoFactorReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(user.Id, &req); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after re-authentication
+// POST /api/v1/auth/2fa/recovery-codes
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, exists := RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req TwoFactorReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(user.Id, &req)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Recovery codes regenerated",
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// respondTwoFactorRequired tells the client to finish login with a second factor
+func respondTwoFactorRequired(c *gin.Context, err *TwoFactorRequiredError) {
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"error":           "Two-factor authentication required",
+		"code":            "2FA_REQUIRED",
+		"challenge_token": err.ChallengeToken,
+		"expires_at":      err.ExpiresAt,
+	})
+}
+
+// respondTwoFactorError maps 2FA management errors to HTTP responses
+func respondTwoFactorError(c *gin.Context, err error) {
+	var rateLimitErr *RateLimitError
+	switch {
+	case errors.As(err, &rateLimitErr):
+		respondRateLimited(c, rateLimitErr)
+	case errors.Is(err, ErrTwoFactorAlreadyEnabled), errors.Is(err, ErrTwoFactorNotEnabled), errors.Is(err, ErrTwoFactorNotSetUp):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_CODE"})
+	case errors.Is(err, ErrReauthenticationFailed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "REAUTH_FAILED"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Two-factor operation failed"})
+	}
+}
+
+// ChangePassword sets a new password and signs out the user's other sessions
+// PUT /api/v1/users/me/password
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	user, exists := RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	sessionID, _ := GetCurrentSessionID(c)
+	if err := h.authService.ChangePassword(user.Id, sessionID, &req); err != nil {
+		respondPasswordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed, other sessions have been signed out"})
+}
+
+// RequestPasswordReset sends a password reset code by SMS
+// POST /api/v1/auth/password/reset/request
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	expiresAt, err := h.authService.RequestPasswordReset(req.PhoneNumber)
+	if err != nil {
+		respondCodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "If an account exists for this number, a reset code has been sent",
+		"expires_at": expiresAt,
+	})
+}
+
+// VerifyPasswordReset exchanges a reset code for a reset token
+// POST /api/v1/auth/password/reset/verify
+func (h *AuthHandler) VerifyPasswordReset(c *gin.Context) {
+	var req PasswordResetVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	resetToken, err := h.authService.VerifyPasswordReset(req.PhoneNumber, req.Code)
+	if err != nil {
+		respondCodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reset code verified",
+		"data":    resetToken,
+	})
+}
+
+// ConfirmPasswordReset sets a new password with a reset token
+// POST /api/v1/auth/password/reset/confirm
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if err := h.authService.ConfirmPasswordReset(&req); err != nil {
+		respondPasswordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset, all sessions have been signed out"})
+}
+
+// respondPasswordError maps password change and reset errors to HTTP responses
+func respondPasswordError(c *gin.Context, err error) {
+	var rateLimitErr *RateLimitError
+	switch {
+	case errors.As(err, &rateLimitErr):
+		respondRateLimited(c, rateLimitErr)
+	case errors.Is(err, ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "WEAK_PASSWORD"})
+	case errors.Is(err, ErrIncorrectPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "INCORRECT_PASSWORD"})
+	case errors.Is(err, ErrInvalidResetToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_RESET_TOKEN"})
+	case errors.Is(err, ErrTooManyAttempts):
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Too many incorrect attempts, request a new reset code",
+			"code":  "TOO_MANY_ATTEMPTS",
+		})
+	case errors.Is(err, ErrSecondFactorRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "2FA_REQUIRED"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+	}
+}
+
+// RequestAccountDeletionCode sends an SMS code confirming account deletion
+// POST /api/v1/users/me/deletion/code
+func (h *AuthHandler) RequestAccountDeletionCode(c *gin.Context) {
+	user, exists := RequireUser(c)
+	if !exists {
+		return
+	}
+
+	expiresAt, err := h.authService.RequestAccountDeletionCode(user.Id)
+	if err != nil {
+		respondDeletionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Deletion code sent",
+		"expires_at": expiresAt,
+	})
+}
+
+// DeleteAccount schedules the current user's account for deletion
+// DELETE /api/v1/users/me
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	user, exists := RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	result, err := h.authService.ScheduleAccountDeletion(user.Id, &req)
+	if err != nil {
+		respondDeletionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account scheduled for deletion, sign in again before then to cancel",
+		"data":    result,
+	})
+}
+
+// respondDeletionError maps account deletion errors to HTTP responses
+func respondDeletionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrDeletionNotAllowed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrSecondFactorRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "2FA_REQUIRED"})
+	default:
+		respondCodeError(c, err)
+	}
+}
+
+// JWKS publishes the public keys that verify access tokens
+// GET /.well-known/jwks.json
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.keys.JWKS())
+}
+
+// RefreshToken exchanges a refresh token for a new token pair
+// POST /api/v1/auth/refresh
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	authResponse, err := h.authService.RefreshTokens(req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRefreshTokenReused):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Refresh token has already been used; please log in again",
+				"code":  "REFRESH_TOKEN_REUSED",
+			})
+		case errors.Is(err, ErrInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or expired refresh token",
+				"code":  "INVALID_REFRESH_TOKEN",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, authResponse)
+}
+
+// Logout revokes the current access token and its session's refresh tokens
+// POST /api/v1/auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims, exists := GetTokenClaims(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+			"code":  "AUTH_REQUIRED",
+		})
+		return
+	}
+
+	if err := h.authService.Logout(claims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every token issued to the current user
+// POST /api/v1/auth/logout-all
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	user, exists := RequireUser(c)
+	if !exists {
+		return
+	}
+
+	if err := h.authService.LogoutAll(user.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices"})
+}
+
+// ListSessions lists the current user's logged-in devices
+// GET /api/v1/auth/sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	user, exists := RequireUser(c)
+	if !exists {
+		return
+	}
+
+	currentSessionID, _ := GetCurrentSessionID(c)
+
+	sessions, err := h.authService.ListSessions(user.Id, currentSessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get sessions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sessions retrieved successfully",
+		"data": gin.H{
+			"sessions": sessions,
+			"count":    len(sessions),
+		},
+	})
+}
+
+// TerminateSession logs out one of the current user's devices
+// DELETE /api/v1/auth/sessions/:session_id
+func (h *AuthHandler) TerminateSession(c *gin.Context) {
+	user, exists := RequireUser(c)
+	if !exists {
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	if err := h.authService.TerminateSession(user.Id, sessionID); err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrSessionNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, gin.H{
+			"error":   "Failed to terminate session",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Session terminated successfully",
+		"session_id": sessionID,
+	})
+}
+
+// deviceInfo collects client details for a new session
+func deviceInfo(c *gin.Context, deviceName string) DeviceInfo {
+	if deviceName == "" {
+		deviceName = c.GetHeader("X-Device-Name")
+	}
+
+	return DeviceInfo{
+		DeviceName: deviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  c.ClientIP(),
+	}
+}
+
 // Add this method to your AuthHandler in internal/auth/handler.go
 func (h *AuthHandler) SearchUsers(c *gin.Context) {
 	user, exists := RequireUser(c)
@@ -55,21 +635,39 @@ func (h *AuthHandler) SearchUsers(c *gin.Context) {
 		return
 	}
 
-	query := c.Query("q")
-	if query == "" || len(query) < 2 {
+	query := strings.TrimSpace(c.Query("q"))
+	if utf8.RuneCountInString(query) < minSearchQuery {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Search query must be at least 2 characters",
+			"error": ErrSearchQueryTooShort.Error(),
 		})
 		return
 	}
 
 	searchType := c.DefaultQuery("type", "all")
 
-	results, err := h.authService.SearchUsers(query, searchType, user.Id)
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	page, err := h.authService.SearchUsers(&UserSearchRequest{
+		Query:  query,
+		Type:   searchType,
+		Cursor: c.Query("cursor"),
+		Limit:  limit,
+	}, user.Id)
 	if err != nil {
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			respondRateLimited(c, rateLimitErr)
+			return
+		}
+		if errors.Is(err, ErrInvalidSearchCursor) || errors.Is(err, ErrSearchQueryTooShort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Search failed",
-			"details": err.Error(),
 		})
 		return
 	}
@@ -79,8 +677,9 @@ func (h *AuthHandler) SearchUsers(c *gin.Context) {
 		"data": gin.H{
 			"query":       query,
 			"type":        searchType,
-			"results":     results,
-			"total":       len(results),
+			"results":     page.Results,
+			"total":       len(page.Results),
+			"next_cursor": page.NextCursor,
 			"searched_by": user.Username,
 		},
 	})
@@ -0,0 +1,421 @@
+// internal/auth/jwks.go
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atharva-navani16/chat-app.git/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported JWT_SIGNING_ALG values
+const (
+	signingAlgHS256 = "HS256"
+	signingAlgEdDSA = "EdDSA"
+	signingAlgRS256 = "RS256"
+)
+
+// Key files in JWT_KEY_DIR are named after their key ID:
+//
+//	<kid>.pem      private key, may sign and verify
+//	<kid>.pub.pem  public key only, verifies tokens of a retired key
+//
+// Rotating keys means adding the new private key, pointing JWT_KEY_ID at it
+// and reloading. The previous key stays in the directory (as a private or
+// public key) until every token it signed has expired.
+const (
+	privateKeyFileSuffix = ".pem"
+	publicKeyFileSuffix  = ".pub.pem"
+)
+
+var (
+	ErrUnknownSigningKey = errors.New("token signed with unknown key")
+	ErrNoSigningKey      = errors.New("no active JWT signing key configured")
+)
+
+// jwtKey is a verification key, with its private half when it can sign
+type jwtKey struct {
+	id         string
+	method     jwt.SigningMethod
+	publicKey  crypto.PublicKey
+	privateKey crypto.PrivateKey
+}
+
+// KeyManager signs access tokens and resolves verification keys by kid
+type KeyManager struct {
+	mutex       sync.RWMutex
+	algorithm   string
+	hmacSecret  []byte
+	legacyUntil time.Time
+	active      *jwtKey
+	keys        map[string]*jwtKey
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeyManager loads signing keys according to JWT_SIGNING_ALG.
+// HS256 (the default) signs with JWT_SECRET and publishes no keys.
+func NewKeyManager(cfg *config.Config) (*KeyManager, error) {
+	algorithm := signingAlgorithm(cfg)
+	switch algorithm {
+	case signingAlgHS256, signingAlgEdDSA, signingAlgRS256:
+	default:
+		return nil, fmt.Errorf("unsupported JWT_SIGNING_ALG %q", algorithm)
+	}
+
+	km := &KeyManager{
+		algorithm: algorithm,
+		keys:      make(map[string]*jwtKey),
+	}
+	if err := km.load(cfg); err != nil {
+		return nil, err
+	}
+	return km, nil
+}
+
+// Reload re-reads the configuration and the configured keys. On error the
+// current keys are kept.
+func (km *KeyManager) Reload() error {
+	cfg, err := config.ReloadConfig()
+	if err != nil {
+		return err
+	}
+	if algorithm := signingAlgorithm(cfg); algorithm != km.algorithm {
+		return fmt.Errorf("JWT_SIGNING_ALG changed from %s to %s, restart to apply", km.algorithm, algorithm)
+	}
+	return km.load(cfg)
+}
+
+// load reads the keys described by cfg and swaps them in
+func (km *KeyManager) load(cfg *config.Config) error {
+	var legacyUntil time.Time
+	if cfg.JWTLegacyHS256Until != "" {
+		until, err := time.Parse(time.RFC3339, cfg.JWTLegacyHS256Until)
+		if err != nil {
+			return fmt.Errorf("invalid JWT_LEGACY_HS256_UNTIL: %v", err)
+		}
+		if cfg.JWTSecret == "" {
+			return errors.New("JWT_SECRET is required while JWT_LEGACY_HS256_UNTIL is set")
+		}
+		legacyUntil = until
+	}
+
+	if km.algorithm == signingAlgHS256 {
+		if cfg.JWTSecret == "" {
+			return errors.New("JWT_SECRET is required for HS256 signing")
+		}
+		km.mutex.Lock()
+		km.hmacSecret = []byte(cfg.JWTSecret)
+		km.mutex.Unlock()
+		return nil
+	}
+
+	keys := make(map[string]*jwtKey)
+
+	if cfg.JWTKeyDir != "" {
+		if err := loadKeyDir(cfg.JWTKeyDir, keys); err != nil {
+			return err
+		}
+	}
+
+	if cfg.JWTPrivateKey != "" {
+		key, err := parsePrivateKey([]byte(cfg.JWTPrivateKey))
+		if err != nil {
+			return fmt.Errorf("invalid JWT_PRIVATE_KEY: %v", err)
+		}
+		key.id = cfg.JWTKeyID
+		if key.id == "" {
+			key.id = keyThumbprint(key.publicKey)
+		}
+		keys[key.id] = key
+	}
+
+	active, err := selectActiveKey(keys, cfg.JWTKeyID)
+	if err != nil {
+		return err
+	}
+	if active.method.Alg() != km.algorithm {
+		return fmt.Errorf("active key %q is %s but JWT_SIGNING_ALG is %s", active.id, active.method.Alg(), km.algorithm)
+	}
+
+	km.mutex.Lock()
+	km.keys = keys
+	km.active = active
+	km.hmacSecret = []byte(cfg.JWTSecret)
+	km.legacyUntil = legacyUntil
+	km.mutex.Unlock()
+
+	log.Printf("🔑 Loaded %d JWT verification keys, signing with %s (%s)", len(keys), active.id, km.algorithm)
+	if !legacyUntil.IsZero() {
+		log.Printf("⚠️ Accepting legacy HS256 tokens until %s", legacyUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Sign creates a signed token for the claims using the active key
+func (km *KeyManager) Sign(claims jwt.MapClaims) (string, error) {
+	km.mutex.RLock()
+	secret, active := km.hmacSecret, km.active
+	km.mutex.RUnlock()
+
+	if km.algorithm == signingAlgHS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	}
+	if active == nil {
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.id
+	return token.SignedString(active.privateKey)
+}
+
+// Keyfunc returns the key that verifies a token. Tokens must use the exact
+// algorithm of the key named by their kid. Tokens without a kid are tried
+// against every key of their algorithm; kid-less HS256 tokens are only
+// accepted in HS256 mode or before JWT_LEGACY_HS256_UNTIL.
+func (km *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	km.mutex.RLock()
+	defer km.mutex.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return km.kidlessKey(token)
+	}
+
+	key, ok := km.keys[kid]
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return key.publicKey, nil
+}
+
+// kidlessKey resolves the keys for a token without a kid. Callers hold the
+// read lock.
+func (km *KeyManager) kidlessKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if token.Method.Alg() != signingAlgHS256 || len(km.hmacSecret) == 0 {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		if km.algorithm != signingAlgHS256 && !time.Now().Before(km.legacyUntil) {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return km.hmacSecret, nil
+	}
+
+	set := jwt.VerificationKeySet{}
+	for _, key := range km.keys {
+		if key.method.Alg() == token.Method.Alg() {
+			set.Keys = append(set.Keys, key.publicKey)
+		}
+	}
+	if len(set.Keys) == 0 {
+		return nil, ErrUnknownSigningKey
+	}
+	return set, nil
+}
+
+// signingAlgorithm returns the configured JWT_SIGNING_ALG, HS256 by default
+func signingAlgorithm(cfg *config.Config) string {
+	if cfg.JWTSigningAlg == "" {
+		return signingAlgHS256
+	}
+	return cfg.JWTSigningAlg
+}
+
+// JWKS returns the public verification keys
+func (km *KeyManager) JWKS() JWKSet {
+	km.mutex.RLock()
+	defer km.mutex.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range km.keys {
+		set.Keys = append(set.Keys, toJWK(key))
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// loadKeyDir reads every key file in dir into keys
+func loadKeyDir(dir string, keys map[string]*jwtKey) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read JWT_KEY_DIR: %v", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, privateKeyFileSuffix) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", name, err)
+		}
+
+		var key *jwtKey
+		if kid, ok := strings.CutSuffix(name, publicKeyFileSuffix); ok {
+			key, err = parsePublicKey(data)
+			if err != nil {
+				return fmt.Errorf("invalid public key %s: %v", name, err)
+			}
+			key.id = kid
+			// A private key with the same ID takes precedence
+			if _, exists := keys[kid]; exists {
+				continue
+			}
+		} else {
+			key, err = parsePrivateKey(data)
+			if err != nil {
+				return fmt.Errorf("invalid private key %s: %v", name, err)
+			}
+			key.id = strings.TrimSuffix(name, privateKeyFileSuffix)
+		}
+
+		keys[key.id] = key
+	}
+
+	return nil
+}
+
+// selectActiveKey picks the signing key: the configured ID, or the only
+// private key when there is exactly one
+func selectActiveKey(keys map[string]*jwtKey, keyID string) (*jwtKey, error) {
+	if keyID != "" {
+		key, ok := keys[keyID]
+		if !ok || key.privateKey == nil {
+			return nil, fmt.Errorf("no private key found for JWT_KEY_ID %q", keyID)
+		}
+		return key, nil
+	}
+
+	var active *jwtKey
+	for _, key := range keys {
+		if key.privateKey == nil {
+			continue
+		}
+		if active != nil {
+			return nil, errors.New("multiple private keys found, set JWT_KEY_ID to choose the signing key")
+		}
+		active = key
+	}
+	if active == nil {
+		return nil, ErrNoSigningKey
+	}
+	return active, nil
+}
+
+// parsePrivateKey reads a PKCS#8 Ed25519 key or a PKCS#1/PKCS#8 RSA key
+func parsePrivateKey(data []byte) (*jwtKey, error) {
+	if key, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		private, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("unsupported EdDSA key type")
+		}
+		return &jwtKey{
+			method:     jwt.SigningMethodEdDSA,
+			publicKey:  private.Public(),
+			privateKey: private,
+		}, nil
+	}
+
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return &jwtKey{
+			method:     jwt.SigningMethodRS256,
+			publicKey:  &key.PublicKey,
+			privateKey: key,
+		}, nil
+	}
+
+	return nil, errors.New("expected an Ed25519 or RSA private key in PEM format")
+}
+
+// parsePublicKey reads an Ed25519 or RSA public key
+func parsePublicKey(data []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch public := key.(type) {
+	case ed25519.PublicKey:
+		return &jwtKey{method: jwt.SigningMethodEdDSA, publicKey: public}, nil
+	case *rsa.PublicKey:
+		return &jwtKey{method: jwt.SigningMethodRS256, publicKey: public}, nil
+	default:
+		return nil, errors.New("expected an Ed25519 or RSA public key")
+	}
+}
+
+// keyThumbprint derives a stable key ID from the public key
+func keyThumbprint(publicKey crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "default"
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8])
+}
+
+// toJWK converts a verification key to its JWK representation
+func toJWK(key *jwtKey) JWK {
+	jwk := JWK{
+		KeyID:     key.id,
+		Use:       "sig",
+		Algorithm: key.method.Alg(),
+	}
+
+	switch public := key.publicKey.(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	}
+
+	return jwk
+}
@@ -14,15 +14,17 @@ import (
 )
 
 type JWTMiddleware struct {
-	config *config.Config
-	db     *sql.DB
+	config      *config.Config
+	db          *sql.DB
+	authService *AuthService
 }
 
 // NewJWTMiddleware creates a new JWT middleware
-func NewJWTMiddleware(config *config.Config, db *sql.DB) *JWTMiddleware {
+func NewJWTMiddleware(config *config.Config, db *sql.DB, authService *AuthService) *JWTMiddleware {
 	return &JWTMiddleware{
-		config: config,
-		db:     db,
+		config:      config,
+		db:          db,
+		authService: authService,
 	}
 }
 
@@ -86,6 +88,13 @@ func (m *JWTMiddleware) AuthRequired() gin.HandlerFunc {
 		// Store user in context for handlers to use
 		c.Set("user", user)
 		c.Set("user_id", userID)
+		c.Set("token_claims", claims)
+
+		m.authService.TouchUser(userID)
+		if sessionID, ok := sessionIDFromClaims(claims); ok {
+			c.Set("session_id", sessionID)
+			m.authService.TouchSession(sessionID)
+		}
 
 		// Continue to next handler
 		c.Next()
@@ -148,32 +157,9 @@ func (m *JWTMiddleware) extractTokenFromHeader(c *gin.Context) (string, error) {
 	return token, nil
 }
 
-// validateToken validates JWT token and returns claims
+// validateToken validates JWT token, checks it has not been revoked and returns claims
 func (m *JWTMiddleware) validateToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return []byte(m.config.JWTSecret), nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	// Check if token is valid
-	if !token.Valid {
-		return nil, jwt.ErrTokenInvalidClaims
-	}
-
-	// Extract claims
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, jwt.ErrTokenInvalidClaims
-	}
-
-	return claims, nil
+	return m.authService.ValidateToken(tokenString)
 }
 
 // getUserByID fetches user from database by ID
@@ -181,17 +167,23 @@ func (m *JWTMiddleware) validateToken(tokenString string) (jwt.MapClaims, error)
 func (m *JWTMiddleware) getUserByID(userID uuid.UUID) (*UserResponse, error) {
 	// Simple query - only get the essential fields that we know exist
 	query := `
-		SELECT id, phone_number, username, first_name, last_name
+		SELECT id, phone_number, username, first_name, COALESCE(last_name, ''),
+		       COALESCE(bio, ''), profile_photo_id, ` + AvatarURLExpr("users") + `
 		FROM users 
-		WHERE id = $1`
+		WHERE id = $1 AND status = 'active'`
 
 	var user UserResponse
+	var profilePhotoID uuid.NullUUID
+	var avatarURL sql.NullString
 	err := m.db.QueryRow(query, userID).Scan(
 		&user.Id,
 		&user.PhoneNumber,
 		&user.Username,
 		&user.FirstName,
 		&user.LastName,
+		&user.Bio,
+		&profilePhotoID,
+		&avatarURL,
 	)
 
 	if err != nil {
@@ -201,9 +193,8 @@ func (m *JWTMiddleware) getUserByID(userID uuid.UUID) (*UserResponse, error) {
 		return nil, fmt.Errorf("database error: %v", err)
 	}
 
-	// Set default values for optional fields
-	user.Bio = ""
-	user.ProfilePhotoID = uuid.Nil
+	user.ProfilePhotoID = profilePhotoID.UUID
+	user.AvatarURL = avatarURL.String
 
 	return &user, nil
 }
@@ -232,6 +223,28 @@ func GetCurrentUserID(c *gin.Context) (uuid.UUID, bool) {
 	return id, ok
 }
 
+// GetCurrentSessionID extracts the current session ID from gin context
+func GetCurrentSessionID(c *gin.Context) (uuid.UUID, bool) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+
+	id, ok := sessionID.(uuid.UUID)
+	return id, ok
+}
+
+// GetTokenClaims extracts the validated access token claims from gin context
+func GetTokenClaims(c *gin.Context) (jwt.MapClaims, bool) {
+	claims, exists := c.Get("token_claims")
+	if !exists {
+		return nil, false
+	}
+
+	mapClaims, ok := claims.(jwt.MapClaims)
+	return mapClaims, ok
+}
+
 // RequireUser ensures user is authenticated (for use in handlers)
 func RequireUser(c *gin.Context) (*UserResponse, bool) {
 	user, exists := GetCurrentUser(c)
@@ -14,6 +14,7 @@ type Users struct {
 	LastName       string    `json:"last_name"`
 	Bio            string    `json:"bio"`
 	ProfilePhotoID uuid.UUID `json:"profile_photo_id"`
+	AvatarURL      string    `json:"avatar_url,omitempty"`
 
 	// Privacy settings
 	IsPublic            bool   `json:"is_public"` // Can be found by username
@@ -29,7 +30,7 @@ type Users struct {
 	// Status
 	IsOnline bool      `json:"is_online"`
 	LastSeen time.Time `json:"last_seen"`
-	Status   string    `json:"status"` // active, deleted, banned
+	Status   string    `json:"status"` // active, deactivated, deleted, banned
 
 	// Metadata
 	CreatedAt time.Time `json:"created_at"`
@@ -44,6 +45,7 @@ type UserResponse struct {
 	LastName       string    `json:"last_name"`
 	Bio            string    `json:"bio"`
 	ProfilePhotoID uuid.UUID `json:"profile_photo_id"`
+	AvatarURL      string    `json:"avatar_url,omitempty"` // Small (160px) variant of the current photo
 }
 
 type CreateUserRequest struct {
@@ -51,22 +53,144 @@ type CreateUserRequest struct {
 	Username    string `json:"username" binding:"required"`
 	FirstName   string `json:"first_name" binding:"required"`
 	LastName    string `json:"last_name" binding:"required"`
-	Password    string `json:"password" binding:"required"`
+	Password    string `json:"password,omitempty"` // Optional: accounts may log in by SMS code only
+	DeviceName  string `json:"device_name,omitempty"`
+
+	// Token returned by /auth/phone/verify for PhoneNumber
+	VerificationToken string `json:"verification_token" binding:"required"`
 }
 
+// LoginRequest carries either a password or, for phone logins, a one-time
+// code requested with purpose "login"
 type LoginRequest struct {
-    PhoneNumber string `json:"phone_number,omitempty"`
-    Username    string `json:"username,omitempty"`
-    Password    string `json:"password" binding:"required"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	Code        string `json:"code,omitempty"`
+	DeviceName  string `json:"device_name,omitempty"`
+}
+
+// DeviceInfo describes the client a session is created for
+type DeviceInfo struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+// Session represents a logged-in device
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	DeviceName   string    `json:"device_name,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	IPAddress    string    `json:"ip_address,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	IsCurrent    bool      `json:"is_current"`
+}
+
+type PhoneCodeRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	Purpose     string `json:"purpose,omitempty"` // verify (default) or login
+}
+
+type PhoneVerifyRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+}
+
+type PhoneVerificationResponse struct {
+	PhoneNumber       string    `json:"phone_number"`
+	VerificationToken string    `json:"verification_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code,omitempty"`          // TOTP code
+	RecoveryCode   string `json:"recovery_code,omitempty"` // Alternative to code
+}
+
+type TwoFactorEnableRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorReauthRequest re-authenticates sensitive 2FA changes
+type TwoFactorReauthRequest struct {
+	Password     string `json:"password,omitempty"` // Required when the account has a password
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password,omitempty"` // Required when the account has a password
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+}
+
+type PasswordResetVerifyRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+}
+
+type PasswordResetTokenResponse struct {
+	ResetToken string    `json:"reset_token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// PasswordResetConfirmRequest sets a new password with a reset token.
+// Accounts with 2FA enabled must also send a TOTP or recovery code.
+type PasswordResetConfirmRequest struct {
+	ResetToken   string `json:"reset_token" binding:"required"`
+	NewPassword  string `json:"new_password" binding:"required"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// DeleteAccountRequest confirms an account deletion with an SMS code.
+// Accounts with 2FA enabled must also send a TOTP or recovery code.
+type DeleteAccountRequest struct {
+	Code          string `json:"code" binding:"required"`
+	TwoFactorCode string `json:"two_factor_code,omitempty"`
+	RecoveryCode  string `json:"recovery_code,omitempty"`
+}
+
+type AccountDeletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 type AuthResponse struct {
-	Token        string      `json:"token"`
-	RefreshToken string      `json:"refresh_token"`
-	ExpiresAt    time.Time   `json:"expires_at"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresAt    time.Time    `json:"expires_at"`
 	User         UserResponse `json:"user"`
 }
 
+// UserSearchRequest is a user search built from the query string
+type UserSearchRequest struct {
+	Query  string
+	Type   string // all, username, name or phone
+	Cursor string // next_cursor of the previous page
+	Limit  int
+}
+
+// UserSearchPage is one page of ranked search results
+type UserSearchPage struct {
+	Results    []UserSearchResult `json:"results"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
 type UserSearchResult struct {
 	ID             uuid.UUID  `json:"id"`
 	Username       string     `json:"username"`
@@ -75,5 +199,6 @@ type UserSearchResult struct {
 	PhoneNumber    string     `json:"phone_number,omitempty"`
 	Bio            string     `json:"bio,omitempty"`
 	IsPublic       bool       `json:"is_public"`
+	AvatarURL      string     `json:"avatar_url,omitempty"`
 	ExistingChatID *uuid.UUID `json:"existing_chat_id,omitempty"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,268 @@
+// internal/auth/otp.go
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// One-time code purposes; each purpose has its own code slot per phone number
+const (
+	otpPurposeVerify = "verify"
+	otpPurposeLogin  = "login"
+	otpPurposeReset  = "reset"
+	otpPurposeDelete = "delete"
+)
+
+const (
+	otpCodeLength        = 6
+	otpCodeTTL           = 5 * time.Minute
+	otpMaxAttempts       = 5
+	otpResendCooldown    = time.Minute
+	otpMaxSendsPerHour   = 5
+	phoneVerificationTTL = 15 * time.Minute
+
+	otpKeyPrefix           = "auth:otp:"            // + purpose:phone, hash of code and attempts
+	otpCooldownKeyPrefix   = "auth:otp_cooldown:"   // + purpose:phone
+	otpSendCountKeyPrefix  = "auth:otp_sends:"      // + phone, sends in the current hour
+	phoneVerifiedKeyPrefix = "auth:phone_verified:" // + token hash, verified phone number
+)
+
+var phoneNumberPattern = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+
+var (
+	ErrInvalidPhoneNumber     = errors.New("phone number must be in E.164 format")
+	ErrInvalidCodePurpose     = errors.New("purpose must be verify or login")
+	ErrInvalidCode            = errors.New("invalid or expired code")
+	ErrInvalidVerification    = errors.New("phone number has not been verified")
+	ErrTooManyAttempts        = errors.New("too many incorrect attempts")
+	ErrSMSDeliveryUnavailable = errors.New("failed to send verification code")
+)
+
+// RateLimitError is returned when a caller must wait before retrying
+type RateLimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+// RequestPhoneCode sends a one-time code by SMS, either to verify a number
+// before registration or to log in without a password
+func (s *AuthService) RequestPhoneCode(phoneNumber, purpose string) (time.Time, error) {
+	if !phoneNumberPattern.MatchString(phoneNumber) {
+		return time.Time{}, ErrInvalidPhoneNumber
+	}
+
+	switch purpose {
+	case "", otpPurposeVerify:
+		return s.sendCode(otpPurposeVerify, phoneNumber)
+
+	case otpPurposeLogin:
+		// Only send to registered numbers, but answer the same way either
+		// way so the endpoint cannot be used to discover accounts
+		var exists bool
+		query := `SELECT EXISTS(SELECT 1 FROM users WHERE phone_number = $1)`
+		if err := s.db.QueryRow(query, phoneNumber).Scan(&exists); err != nil {
+			return time.Time{}, err
+		}
+		if !exists {
+			return s.withholdCode(otpPurposeLogin, phoneNumber)
+		}
+		return s.sendCode(otpPurposeLogin, phoneNumber)
+
+	default:
+		return time.Time{}, ErrInvalidCodePurpose
+	}
+}
+
+// VerifyPhoneCode checks a verification code and returns a short-lived token
+// proving ownership of the number, to be presented at registration
+func (s *AuthService) VerifyPhoneCode(phoneNumber, code string) (*PhoneVerificationResponse, error) {
+	if !phoneNumberPattern.MatchString(phoneNumber) {
+		return nil, ErrInvalidPhoneNumber
+	}
+
+	if err := s.checkCode(otpPurposeVerify, phoneNumber, code); err != nil {
+		return nil, err
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	expiresAt := time.Now().Add(phoneVerificationTTL)
+	if err := s.rdb.Set(ctx, phoneVerifiedKeyPrefix+hashToken(token), phoneNumber, phoneVerificationTTL).Err(); err != nil {
+		return nil, err
+	}
+
+	return &PhoneVerificationResponse{
+		PhoneNumber:       phoneNumber,
+		VerificationToken: token,
+		ExpiresAt:         expiresAt,
+	}, nil
+}
+
+// checkPhoneVerification confirms a verification token was issued for the number
+func (s *AuthService) checkPhoneVerification(token, phoneNumber string) error {
+	ctx := context.Background()
+
+	verifiedPhone, err := s.rdb.Get(ctx, phoneVerifiedKeyPrefix+hashToken(token)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return ErrInvalidVerification
+		}
+		return err
+	}
+
+	if verifiedPhone != phoneNumber {
+		return ErrInvalidVerification
+	}
+	return nil
+}
+
+// consumePhoneVerification makes a verification token unusable
+func (s *AuthService) consumePhoneVerification(token string) {
+	s.rdb.Del(context.Background(), phoneVerifiedKeyPrefix+hashToken(token))
+}
+
+// sendCode generates a code for the purpose, stores its hash and sends it by SMS
+func (s *AuthService) sendCode(purpose, phoneNumber string) (time.Time, error) {
+	ctx := context.Background()
+	slot := purpose + ":" + phoneNumber
+
+	if err := s.throttleCodeSend(slot, phoneNumber); err != nil {
+		return time.Time{}, err
+	}
+
+	code, err := generateNumericCode(otpCodeLength)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, otpKeyPrefix+slot)
+	pipe.HSet(ctx, otpKeyPrefix+slot, "code", hashToken(code), "attempts", 0)
+	pipe.Expire(ctx, otpKeyPrefix+slot, otpCodeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	body := fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(otpCodeTTL.Minutes()))
+	if err := s.sms.SendSMS(ctx, phoneNumber, body); err != nil {
+		s.rdb.Del(ctx, otpKeyPrefix+slot, otpCooldownKeyPrefix+slot)
+		return time.Time{}, fmt.Errorf("%w: %v", ErrSMSDeliveryUnavailable, err)
+	}
+
+	return time.Now().Add(otpCodeTTL), nil
+}
+
+// withholdCode answers a code request for a number that must not receive
+// one exactly like sendCode would, cooldowns included, so the response does
+// not reveal whether the number is registered
+func (s *AuthService) withholdCode(purpose, phoneNumber string) (time.Time, error) {
+	if err := s.throttleCodeSend(purpose+":"+phoneNumber, phoneNumber); err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(otpCodeTTL), nil
+}
+
+// throttleCodeSend applies the per-purpose resend cooldown and the hourly
+// cap on codes sent to a number
+func (s *AuthService) throttleCodeSend(slot, phoneNumber string) error {
+	ctx := context.Background()
+
+	// Per-purpose resend cooldown
+	acquired, err := s.rdb.SetNX(ctx, otpCooldownKeyPrefix+slot, 1, otpResendCooldown).Result()
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		ttl, _ := s.rdb.TTL(ctx, otpCooldownKeyPrefix+slot).Result()
+		return &RateLimitError{Reason: "code recently sent", RetryAfter: positiveDuration(ttl)}
+	}
+
+	// Hourly cap across all purposes to limit SMS pumping
+	sendCountKey := otpSendCountKeyPrefix + phoneNumber
+	sends, err := s.rdb.Incr(ctx, sendCountKey).Result()
+	if err != nil {
+		return err
+	}
+	if sends == 1 {
+		s.rdb.Expire(ctx, sendCountKey, time.Hour)
+	}
+	if sends > otpMaxSendsPerHour {
+		ttl, _ := s.rdb.TTL(ctx, sendCountKey).Result()
+		return &RateLimitError{Reason: "too many codes requested", RetryAfter: positiveDuration(ttl)}
+	}
+
+	return nil
+}
+
+// checkCode verifies a code for the purpose, consuming it on success and
+// discarding it once too many wrong guesses have been made
+func (s *AuthService) checkCode(purpose, phoneNumber, code string) error {
+	ctx := context.Background()
+	key := otpKeyPrefix + purpose + ":" + phoneNumber
+
+	// Count the attempt before comparing so concurrent guesses cannot exceed the limit
+	pipe := s.rdb.TxPipeline()
+	attemptsCmd := pipe.HIncrBy(ctx, key, "attempts", 1)
+	codeCmd := pipe.HGet(ctx, key, "code")
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return err
+	}
+
+	storedHash := codeCmd.Val()
+	if storedHash == "" {
+		// No code outstanding (HINCRBY created an empty hash)
+		s.rdb.Del(ctx, key)
+		return ErrInvalidCode
+	}
+
+	if attemptsCmd.Val() > otpMaxAttempts {
+		s.rdb.Del(ctx, key)
+		return ErrTooManyAttempts
+	}
+
+	if subtle.ConstantTimeCompare([]byte(storedHash), []byte(hashToken(code))) != 1 {
+		return ErrInvalidCode
+	}
+
+	s.rdb.Del(ctx, key)
+	return nil
+}
+
+// generateNumericCode returns a random zero-padded decimal code
+func generateNumericCode(length int) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < length; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+// positiveDuration clamps Redis TTL results (which may be negative sentinels)
+func positiveDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Second
+	}
+	return d
+}
@@ -0,0 +1,230 @@
+// internal/auth/password.go
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores anything longer
+
+	passwordResetTTL         = 15 * time.Minute
+	passwordResetMaxAttempts = 5
+	passwordResetKeyPrefix   = "auth:password_reset:" // + token hash, user ID and attempts
+)
+
+var (
+	ErrWeakPassword         = errors.New("password must be between 8 and 72 characters")
+	ErrIncorrectPassword    = errors.New("current password is incorrect")
+	ErrInvalidResetToken    = errors.New("invalid or expired reset token")
+	ErrSecondFactorRequired = errors.New("two-factor code required")
+)
+
+// validatePassword enforces the password policy
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
+// ChangePassword sets a new password and signs out every other session.
+// Accounts created without a password can set one without a current password.
+func (s *AuthService) ChangePassword(userID uuid.UUID, currentSessionID uuid.UUID, req *ChangePasswordRequest) error {
+	if err := validatePassword(req.NewPassword); err != nil {
+		return err
+	}
+
+	var passwordHash sql.NullString
+	if err := s.db.QueryRow(`SELECT password_hash FROM users WHERE id = $1`, userID).Scan(&passwordHash); err != nil {
+		return err
+	}
+	if passwordHash.Valid && passwordHash.String != "" {
+		if err := s.checkCredentialAttempt(userID); err != nil {
+			return err
+		}
+		if !s.checkPassword(req.CurrentPassword, passwordHash.String) {
+			return ErrIncorrectPassword
+		}
+		s.resetCredentialAttempts(userID)
+	}
+
+	if err := s.setPassword(userID, req.NewPassword); err != nil {
+		return err
+	}
+
+	return s.revokeOtherSessions(userID, currentSessionID)
+}
+
+// RequestPasswordReset sends a reset code to the account's phone number.
+// Unknown numbers get the same response and cooldowns so accounts cannot be
+// enumerated.
+func (s *AuthService) RequestPasswordReset(phoneNumber string) (time.Time, error) {
+	if !phoneNumberPattern.MatchString(phoneNumber) {
+		return time.Time{}, ErrInvalidPhoneNumber
+	}
+
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE phone_number = $1)`
+	if err := s.db.QueryRow(query, phoneNumber).Scan(&exists); err != nil {
+		return time.Time{}, err
+	}
+	if !exists {
+		return s.withholdCode(otpPurposeReset, phoneNumber)
+	}
+
+	return s.sendCode(otpPurposeReset, phoneNumber)
+}
+
+// VerifyPasswordReset exchanges a reset code for a single-use reset token
+func (s *AuthService) VerifyPasswordReset(phoneNumber, code string) (*PasswordResetTokenResponse, error) {
+	if !phoneNumberPattern.MatchString(phoneNumber) {
+		return nil, ErrInvalidPhoneNumber
+	}
+
+	if err := s.checkCode(otpPurposeReset, phoneNumber, code); err != nil {
+		return nil, err
+	}
+
+	var userID uuid.UUID
+	if err := s.db.QueryRow(`SELECT id FROM users WHERE phone_number = $1`, phoneNumber).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidCode
+		}
+		return nil, err
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	key := passwordResetKeyPrefix + hashToken(token)
+
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userID.String(), "attempts", 0)
+	pipe.Expire(ctx, key, passwordResetTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return &PasswordResetTokenResponse{
+		ResetToken: token,
+		ExpiresAt:  time.Now().Add(passwordResetTTL),
+	}, nil
+}
+
+// ConfirmPasswordReset sets a new password using a reset token and signs out
+// every session. Accounts with 2FA must also present a second factor; the
+// token is discarded after too many attempts.
+func (s *AuthService) ConfirmPasswordReset(req *PasswordResetConfirmRequest) error {
+	if err := validatePassword(req.NewPassword); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	key := passwordResetKeyPrefix + hashToken(req.ResetToken)
+
+	// Count the attempt before checking the second factor so concurrent
+	// guesses cannot exceed the limit
+	pipe := s.rdb.TxPipeline()
+	attemptsCmd := pipe.HIncrBy(ctx, key, "attempts", 1)
+	userIDCmd := pipe.HGet(ctx, key, "user_id")
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return err
+	}
+
+	userID, err := uuid.Parse(userIDCmd.Val())
+	if err != nil {
+		// No token outstanding (HINCRBY created an empty hash)
+		s.rdb.Del(ctx, key)
+		return ErrInvalidResetToken
+	}
+
+	if attemptsCmd.Val() > passwordResetMaxAttempts {
+		s.rdb.Del(ctx, key)
+		return ErrTooManyAttempts
+	}
+
+	// An SMS code alone must not bypass 2FA
+	enabled, err := s.isTwoFactorEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		if err := s.checkCredentialAttempt(userID); err != nil {
+			return err
+		}
+		ok, err := s.checkSecondFactor(userID, req.Code, req.RecoveryCode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrSecondFactorRequired
+		}
+		s.resetCredentialAttempts(userID)
+	}
+
+	// Reset tokens are single use; whoever deletes the key wins
+	if deleted, err := s.rdb.Del(ctx, key).Result(); err != nil || deleted == 0 {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.setPassword(userID, req.NewPassword); err != nil {
+		return err
+	}
+
+	return s.LogoutAll(userID)
+}
+
+// setPassword hashes and stores a new password
+func (s *AuthService) setPassword(userID uuid.UUID, password string) error {
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1`
+	_, err = s.db.Exec(query, userID, hash)
+	return err
+}
+
+// revokeOtherSessions terminates every session of the user except one
+func (s *AuthService) revokeOtherSessions(userID uuid.UUID, keepSessionID uuid.UUID) error {
+	query := `
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND id != $2 AND revoked_at IS NULL
+		RETURNING id`
+
+	rows, err := s.db.Query(query, userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+
+	var sessionIDs []uuid.UUID
+	for rows.Next() {
+		var sessionID uuid.UUID
+		if err := rows.Scan(&sessionID); err != nil {
+			rows.Close()
+			return err
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	rows.Close()
+
+	for _, sessionID := range sessionIDs {
+		if err := s.revokeSessionTokens(sessionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+// internal/auth/revocation.go
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys used to revoke access tokens before they expire
+const (
+	revokedTokenKeyPrefix    = "auth:revoked_jti:"      // + jti, set on logout
+	tokenGenerationKeyPrefix = "auth:token_generation:" // + user ID, bumped by logout-all
+)
+
+// ValidateToken parses an access token and rejects it if it has been revoked
+func (s *AuthService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, s.keys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if token is valid
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	// Extract claims
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	revoked, err := s.isTokenRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	return claims, nil
+}
+
+// isTokenRevoked checks the Redis denylist for the token's jti and session,
+// and whether a logout-all has bumped the user's token generation since the
+// token was issued
+func (s *AuthService) isTokenRevoked(claims jwt.MapClaims) (bool, error) {
+	ctx := context.Background()
+
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(string)
+	sessionID, _ := claims["sid"].(string)
+	if jti == "" || userID == "" || sessionID == "" {
+		// Tokens minted before revocation support cannot be revoked individually
+		return false, nil
+	}
+
+	pipe := s.rdb.Pipeline()
+	revokedCmd := pipe.Exists(ctx, revokedTokenKeyPrefix+jti, revokedSessionKeyPrefix+sessionID)
+	generationCmd := pipe.Get(ctx, tokenGenerationKeyPrefix+userID)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return false, fmt.Errorf("revocation check failed: %v", err)
+	}
+
+	if revokedCmd.Val() > 0 {
+		return true, nil
+	}
+
+	if current, err := strconv.ParseInt(generationCmd.Val(), 10, 64); err == nil {
+		generation, _ := claims["gen"].(float64)
+		if int64(generation) < current {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Logout revokes the presented access token and the refresh tokens of its session
+func (s *AuthService) Logout(claims jwt.MapClaims) error {
+	ctx := context.Background()
+
+	jti, _ := claims["jti"].(string)
+	if jti != "" {
+		ttl := time.Until(tokenExpiry(claims))
+		if ttl > 0 {
+			if err := s.rdb.Set(ctx, revokedTokenKeyPrefix+jti, 1, ttl).Err(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if sessionID, ok := sessionIDFromClaims(claims); ok {
+		query := `UPDATE user_sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+		if _, err := s.db.Exec(query, sessionID); err != nil {
+			return err
+		}
+		return s.revokeSessionTokens(sessionID)
+	}
+
+	return nil
+}
+
+// LogoutAll revokes every access and refresh token the user currently holds
+func (s *AuthService) LogoutAll(userID uuid.UUID) error {
+	ctx := context.Background()
+
+	// Every access token issued so far carries an older generation and is
+	// rejected. The counter never expires so generations only move forward.
+	if err := s.rdb.Incr(ctx, tokenGenerationKeyPrefix+userID.String()).Err(); err != nil {
+		return err
+	}
+
+	query := `UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := s.db.Exec(query, userID); err != nil {
+		return err
+	}
+
+	query = `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := s.db.Exec(query, userID); err != nil {
+		return err
+	}
+
+	if s.notifier != nil {
+		s.notifier.DisconnectUser(userID)
+	}
+
+	return nil
+}
+
+// tokenGeneration returns the user's current token generation, stamped into
+// new access tokens so a later logout-all can revoke them
+func (s *AuthService) tokenGeneration(userID uuid.UUID) (int64, error) {
+	generation, err := s.rdb.Get(context.Background(), tokenGenerationKeyPrefix+userID.String()).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return generation, err
+}
+
+// tokenExpiry returns the exp claim, or now if it is missing
+func tokenExpiry(claims jwt.MapClaims) time.Time {
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Now()
+	}
+	return exp.Time
+}
+
+// sessionIDFromClaims extracts the sid claim
+func sessionIDFromClaims(claims jwt.MapClaims) (uuid.UUID, bool) {
+	sid, ok := claims["sid"].(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(sid)
+	return id, err == nil
+}
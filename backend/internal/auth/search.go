@@ -0,0 +1,342 @@
+// internal/auth/search.go
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	minSearchQuery     = 2 // characters
+)
+
+// Looking up phone numbers reveals which have accounts. Search by phone and
+// contact imports share one daily allowance of numbers per user.
+const (
+	PhoneLookupLimit     = 1000
+	PhoneLookupWindow    = 24 * time.Hour
+	PhoneLookupKeyPrefix = "contacts:import_numbers:" // + user ID
+)
+
+var (
+	ErrInvalidSearchCursor = errors.New("invalid search cursor")
+	ErrSearchQueryTooShort = fmt.Errorf("search query must be at least %d characters", minSearchQuery)
+)
+
+// Search result tiers, best first
+const (
+	searchTierExactUsername = 0
+	searchTierContact       = 1
+	searchTierPublic        = 2
+)
+
+// fullNameExpr is the normalised full name of users row u. The trigram index
+// in migration 016 is built on exactly this expression.
+const fullNameExpr = `LOWER(u.first_name || ' ' || COALESCE(u.last_name, ''))`
+
+// notBlockedBySearcher hides users who blocked the searcher ($1) from search
+const notBlockedBySearcher = `NOT EXISTS(
+				SELECT 1 FROM user_contacts b
+				WHERE b.user_id = u.id AND b.contact_user_id = $1 AND b.is_blocked = true)`
+
+// visiblePhoneNumber is the phone number of users row u if its
+// phone_number_privacy lets the searcher ($1) see it, and NULL otherwise
+const visiblePhoneNumber = `CASE WHEN COALESCE(u.phone_number_privacy, 'contacts') = 'everyone'
+			       OR (COALESCE(u.phone_number_privacy, 'contacts') = 'contacts' AND EXISTS(
+			           SELECT 1 FROM user_contacts pc
+			           WHERE pc.user_id = u.id AND pc.contact_user_id = $1
+			             AND pc.is_contact = true AND pc.is_blocked = false))
+			     THEN u.phone_number END`
+
+// searchCursor is the position of the last result of a page
+type searchCursor struct {
+	tier  int
+	score float64
+	id    uuid.UUID
+}
+
+func (c searchCursor) encode() string {
+	raw := fmt.Sprintf("%d|%s|%s", c.tier, strconv.FormatFloat(c.score, 'g', -1, 64), c.id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchCursor(value string) (*searchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, ErrInvalidSearchCursor
+	}
+
+	var cursor searchCursor
+	if cursor.tier, err = strconv.Atoi(parts[0]); err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+	if cursor.score, err = strconv.ParseFloat(parts[1], 64); err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+	if cursor.id, err = uuid.Parse(parts[2]); err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+	return &cursor, nil
+}
+
+// SearchUsers finds users by username, name or phone number. Results are
+// ranked by tier (exact username, then the searcher's contacts, then public
+// accounts) and then by trigram similarity, and paged with an opaque cursor.
+// Accounts that are not public are only found by people who have them as a
+// contact.
+func (s *AuthService) SearchUsers(req *UserSearchRequest, currentUserID uuid.UUID) (*UserSearchPage, error) {
+	searchType := strings.ToLower(req.Type)
+	query := strings.TrimPrefix(strings.TrimSpace(req.Query), "@")
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	var cursor *searchCursor
+	if req.Cursor != "" {
+		var err error
+		if cursor, err = decodeSearchCursor(req.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []UserSearchResult
+	var nextCursor string
+	var err error
+	if searchType == "phone" {
+		// Phone lookups are exact and only match discoverable accounts
+		results, err = s.searchByPhone(req.Query, currentUserID)
+	} else if utf8.RuneCountInString(query) < minSearchQuery {
+		return nil, ErrSearchQueryTooShort
+	} else {
+		results, nextCursor, err = s.searchRanked(query, searchType, currentUserID, cursor, limit)
+	}
+	if err != nil {
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			slog.Error("user search failed", "type", searchType, "user_id", currentUserID, "error", err)
+		}
+		return nil, err
+	}
+
+	if err := s.attachExistingChats(currentUserID, results); err != nil {
+		slog.Error("user search failed to load existing chats", "user_id", currentUserID, "error", err)
+		return nil, err
+	}
+
+	slog.Debug("user search",
+		"type", searchType,
+		"user_id", currentUserID,
+		"results", len(results),
+		"paged", cursor != nil,
+		"has_more", nextCursor != "",
+	)
+
+	return &UserSearchPage{Results: results, NextCursor: nextCursor}, nil
+}
+
+// searchRanked runs a trigram search over usernames, full names or both
+func (s *AuthService) searchRanked(query, searchType string, currentUserID uuid.UUID, cursor *searchCursor, limit int) ([]UserSearchResult, string, error) {
+	var match, score string
+	switch searchType {
+	case "username":
+		match = `(LOWER(u.username) LIKE $3 ESCAPE '\' OR LOWER(u.username) % $2)`
+		score = `similarity(LOWER(u.username), $2)`
+	case "name":
+		match = `(` + fullNameExpr + ` LIKE $3 ESCAPE '\' OR $2 <% ` + fullNameExpr + `)`
+		score = `word_similarity($2, ` + fullNameExpr + `)`
+	default:
+		match = `(LOWER(u.username) LIKE $3 ESCAPE '\' OR LOWER(u.username) % $2
+			OR ` + fullNameExpr + ` LIKE $3 ESCAPE '\' OR $2 <% ` + fullNameExpr + `)`
+		score = `GREATEST(similarity(LOWER(u.username), $2), word_similarity($2, ` + fullNameExpr + `))`
+	}
+
+	lowered := strings.ToLower(query)
+	args := []interface{}{currentUserID, lowered, "%" + escapeLike(lowered) + "%"}
+
+	after := ""
+	if cursor != nil {
+		args = append(args, cursor.tier, -cursor.score, cursor.id)
+		after = `WHERE (r.tier, -r.score, r.id) > ($4, $5, $6)`
+	}
+	args = append(args, limit+1) // +1 to check if there are more
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT r.id, r.username, r.first_name, r.last_name, r.phone_number, r.bio, r.is_public,
+		       r.avatar_url, r.tier, r.score
+		FROM (
+			SELECT u.id, COALESCE(u.username, '') AS username, u.first_name,
+			       COALESCE(u.last_name, '') AS last_name, %s AS phone_number, u.bio, u.is_public,
+			       %s AS avatar_url,
+			       CASE WHEN LOWER(u.username) = $2 THEN %d
+			            WHEN uc.user_id IS NOT NULL THEN %d
+			            ELSE %d END AS tier,
+			       (%s)::float8 AS score
+			FROM users u
+			LEFT JOIN user_contacts uc ON uc.user_id = $1 AND uc.contact_user_id = u.id
+			     AND uc.is_contact = true AND uc.is_blocked = false
+			WHERE %s
+			  AND u.id != $1 AND u.status = 'active' AND %s
+			  AND (u.is_public = true OR uc.user_id IS NOT NULL)
+		) r
+		%s
+		ORDER BY r.tier, r.score DESC, r.id
+		LIMIT $%d`,
+		visiblePhoneNumber, AvatarURLExpr("u"), searchTierExactUsername, searchTierContact, searchTierPublic,
+		score, match, notBlockedBySearcher, after, len(args))
+
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	results := []UserSearchResult{}
+	var last searchCursor
+	for rows.Next() {
+		var user UserSearchResult
+		var position searchCursor
+		var phoneNumber, bio, avatarURL sql.NullString
+
+		err := rows.Scan(
+			&user.ID, &user.Username, &user.FirstName, &user.LastName,
+			&phoneNumber, &bio, &user.IsPublic, &avatarURL,
+			&position.tier, &position.score,
+		)
+		if err != nil {
+			return nil, "", err
+		}
+		position.id = user.ID
+
+		if len(results) == limit {
+			// The extra row only tells us there is another page
+			return results, last.encode(), rows.Err()
+		}
+
+		user.PhoneNumber = phoneNumber.String
+		user.Bio = bio.String
+		user.AvatarURL = avatarURL.String
+		results = append(results, user)
+		last = position
+	}
+
+	return results, "", rows.Err()
+}
+
+// searchByPhone finds the discoverable account with an exact phone number.
+// Every lookup counts against the allowance shared with contact imports.
+func (s *AuthService) searchByPhone(phoneNumber string, currentUserID uuid.UUID) ([]UserSearchResult, error) {
+	result, err := s.limiter.Allow(context.Background(), PhoneLookupKeyPrefix+currentUserID.String(), PhoneLookupLimit, PhoneLookupWindow)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Allowed {
+		return nil, &RateLimitError{Reason: "too many phone number lookups", RetryAfter: positiveDuration(result.RetryAfter)}
+	}
+
+	query := `
+		SELECT u.id, COALESCE(u.username, ''), u.first_name, COALESCE(u.last_name, ''),
+		       ` + visiblePhoneNumber + `, u.bio, u.is_public, ` + AvatarURLExpr("u") + `
+		FROM users u
+		WHERE u.phone_number = $2 AND u.id != $1 AND u.status = 'active'
+		  AND ` + notBlockedBySearcher + ` AND u.allow_phone_discovery = true`
+
+	var user UserSearchResult
+	var phone, bio, avatarURL sql.NullString
+	err = s.db.QueryRow(query, currentUserID, strings.TrimSpace(phoneNumber)).Scan(
+		&user.ID, &user.Username, &user.FirstName, &user.LastName,
+		&phone, &bio, &user.IsPublic, &avatarURL,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return []UserSearchResult{}, nil
+		}
+		return nil, err
+	}
+
+	user.PhoneNumber = phone.String
+	user.Bio = bio.String
+	user.AvatarURL = avatarURL.String
+	return []UserSearchResult{user}, nil
+}
+
+// attachExistingChats sets ExistingChatID on results the searcher already
+// has a private chat with
+func (s *AuthService) attachExistingChats(currentUserID uuid.UUID, results []UserSearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	userIDs := make([]uuid.UUID, len(results))
+	for i, result := range results {
+		userIDs[i] = result.ID
+	}
+
+	chats, err := s.findExistingPrivateChats(currentUserID, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range results {
+		if chatID, ok := chats[results[i].ID]; ok {
+			results[i].ExistingChatID = &chatID
+		}
+	}
+	return nil
+}
+
+// findExistingPrivateChats maps each of otherUserIDs to the private chat it
+// shares with userID, if there is one
+func (s *AuthService) findExistingPrivateChats(userID uuid.UUID, otherUserIDs []uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	query := `
+		SELECT cm2.user_id, c.id FROM chats c
+		JOIN chat_members cm1 ON c.id = cm1.chat_id
+		JOIN chat_members cm2 ON c.id = cm2.chat_id
+		WHERE c.type = 'private'
+		AND cm1.user_id = $1 AND cm1.status = 'active'
+		AND cm2.user_id = ANY($2) AND cm2.status = 'active'`
+
+	rows, err := s.db.Query(query, userID, pq.Array(otherUserIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chats := make(map[uuid.UUID]uuid.UUID)
+	for rows.Next() {
+		var otherUserID, chatID uuid.UUID
+		if err := rows.Scan(&otherUserID, &chatID); err != nil {
+			return nil, err
+		}
+		chats[otherUserID] = chatID
+	}
+
+	return chats, rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards so the query matches literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
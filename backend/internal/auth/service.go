@@ -1,92 +1,199 @@
 package auth
 
 import (
-	"crypto/rand"
 	"database/sql"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/atharva-navani16/chat-app.git/internal/config"
+	"github.com/atharva-navani16/chat-app.git/internal/shared/ratelimit"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
-	"golang.org/x/crypto/nacl/box"
 )
 
 type AuthService struct {
-	db     *sql.DB
-	rdb    *redis.Client
-	config *config.Config
+	db       *sql.DB
+	rdb      *redis.Client
+	config   *config.Config
+	notifier SessionNotifier
+	sms      SMSSender
+	limiter  *ratelimit.Limiter
+	keys     *KeyManager
+	names    *UsernamePolicy
 }
 
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrMissingCredentials = errors.New("phone number or username and a password or code are required")
+	ErrAccountExists      = errors.New("phone number or username is already taken")
+	ErrAccountBanned      = errors.New("this account has been banned")
+
+	errUserNotFound = errors.New("user not found")
+)
+
 // NewAuthService creates a new auth service
-func NewAuthService(db *sql.DB, rdb *redis.Client, config *config.Config) *AuthService {
+func NewAuthService(db *sql.DB, rdb *redis.Client, config *config.Config, notifier SessionNotifier, sms SMSSender, keys *KeyManager) *AuthService {
 	return &AuthService{
-		db:     db,
-		rdb:    rdb,
-		config: config,
+		db:       db,
+		rdb:      rdb,
+		config:   config,
+		notifier: notifier,
+		sms:      sms,
+		limiter:  ratelimit.NewLimiter(rdb),
+		keys:     keys,
+		names:    NewUsernamePolicy(db, config),
 	}
 }
 
 // Register creates a new user account
-func (s *AuthService) Register(req *CreateUserRequest) (*AuthResponse, error) {
-	// Step 1: Hash password
-	hashedPassword, err := s.hashPassword(req.Password)
+func (s *AuthService) Register(req *CreateUserRequest, device DeviceInfo) (*AuthResponse, error) {
+	// Step 0: Require proof that the caller owns the phone number
+	if !phoneNumberPattern.MatchString(req.PhoneNumber) {
+		return nil, ErrInvalidPhoneNumber
+	}
+	if err := s.checkPhoneVerification(req.VerificationToken, req.PhoneNumber); err != nil {
+		return nil, err
+	}
+	// Only count attempts for verified numbers, so nobody else can use up a
+	// number's registration allowance
+	if err := s.checkRegistrationAllowed(req.PhoneNumber, device.IPAddress); err != nil {
+		return nil, err
+	}
+	if err := s.names.Check(req.Username, uuid.Nil); err != nil {
+		return nil, err
+	}
+	chatTaken, err := isChatUsername(s.db, req.Username)
 	if err != nil {
 		return nil, err
 	}
+	if chatTaken {
+		return nil, ErrAccountExists
+	}
 
-	// Step 2: Generate crypto keys
-	publicKey, signedPreKey, signature := s.generateCryptoKeys()
+	// Step 1: Hash password (optional for code-only accounts)
+	var hashedPassword sql.NullString
+	if req.Password != "" {
+		if err := validatePassword(req.Password); err != nil {
+			return nil, err
+		}
+		hash, err := s.hashPassword(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		hashedPassword = sql.NullString{String: hash, Valid: true}
+	}
 
-	// Step 3: Save to database
-	user, err := s.createUserInDB(req, hashedPassword, publicKey, signedPreKey, signature)
+	// Step 2: Save to database. E2E keys are generated and uploaded by the
+	// client afterwards via PUT /api/v1/keys.
+	user, err := s.createUserInDB(req, hashedPassword)
 	if err != nil {
 		return nil, err
 	}
+	s.consumePhoneVerification(req.VerificationToken)
+
+	// Step 3: Start a session and issue tokens
+	return s.issueTokens(user, device)
+}
+
+// Login authenticates a user and returns a token
+func (s *AuthService) Login(req *LoginRequest, device DeviceInfo) (*AuthResponse, error) {
+	if req.PhoneNumber == "" && req.Username == "" {
+		return nil, ErrMissingCredentials
+	}
+	if req.Password == "" && req.Code == "" {
+		return nil, ErrMissingCredentials
+	}
 
-	// Step 4: Generate JWT token
-	token, expiresAt, err := s.generateJWT(user.Id)
+	// Step 0: Refuse attempts while the account or IP is locked out
+	identifier := loginIdentifier(req)
+	if err := s.checkLoginAllowed(identifier, device.IPAddress); err != nil {
+		return nil, err
+	}
+
+	// Step 1: Authenticate with a one-time code or a password
+	var user *Users
+	var err error
+	if req.Code != "" {
+		user, err = s.authenticateWithCode(req)
+	} else {
+		user, err = s.authenticateWithPassword(req)
+	}
 	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			s.recordLoginFailure(identifier, device.IPAddress)
+		}
 		return nil, err
 	}
 
-	// Step 5: Return response
-	return &AuthResponse{
-		Token:     token,
-		ExpiresAt: expiresAt,
-		User:      s.userToResponse(user),
-	}, nil
+	// Banned accounts are only told so once they prove who they are
+	if user.Status == UserStatusBanned {
+		return nil, ErrAccountBanned
+	}
+
+	// Step 2: Start a session, unless a second factor is still required.
+	// Failures are only cleared once the whole login has succeeded.
+	return s.completeLogin(user, identifier, device)
 }
 
-// Login authenticates a user and returns a token
-func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
+// authenticateWithPassword checks a password login. Unknown accounts and
+// wrong passwords fail the same way, in the same time.
+func (s *AuthService) authenticateWithPassword(req *LoginRequest) (*Users, error) {
 	// Step 1: Find user by phone or username
 	user, err := s.findUserByCredentials(req)
 	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			simulatePasswordCheck(req.Password)
+			return nil, ErrInvalidCredentials
+		}
 		return nil, err
 	}
 
-	// Step 2: Check password
+	// Step 2: Check password (code-only accounts have none)
+	if user.PasswordHash == "" {
+		simulatePasswordCheck(req.Password)
+		return nil, ErrInvalidCredentials
+	}
 	if !s.checkPassword(req.Password, user.PasswordHash) {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// authenticateWithCode checks a phone login with a one-time login code
+func (s *AuthService) authenticateWithCode(req *LoginRequest) (*Users, error) {
+	if req.PhoneNumber == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	// Step 1: Check the code sent by RequestPhoneCode
+	if err := s.checkCode(otpPurposeLogin, req.PhoneNumber, req.Code); err != nil {
+		if errors.Is(err, ErrInvalidCode) || errors.Is(err, ErrTooManyAttempts) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
 	}
 
-	// Step 3: Generate JWT
-	token, expiresAt, err := s.generateJWT(user.Id)
+	// Step 2: Find the account
+	user, err := s.findUserByCredentials(&LoginRequest{PhoneNumber: req.PhoneNumber})
 	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
 		return nil, err
 	}
 
-	// Step 4: Return response
-	return &AuthResponse{
-		Token:     token,
-		ExpiresAt: expiresAt,
-		User:      s.userToResponse(user),
-	}, nil
+	// Receiving the code proves ownership of the number
+	query := `UPDATE users SET phone_verified_at = COALESCE(phone_verified_at, NOW()) WHERE id = $1`
+	if _, err := s.db.Exec(query, user.Id); err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
 // hashPassword hashes a plain text password
@@ -101,39 +208,28 @@ func (s *AuthService) checkPassword(password, hash string) bool {
 	return err == nil
 }
 
-// generateCryptoKeys generates encryption keys for the user
-func (s *AuthService) generateCryptoKeys() ([]byte, []byte, []byte) {
-	publicKey, _, err := box.GenerateKey(rand.Reader)
-	if err != nil {
-		panic("Failed to generate crypto keys: " + err.Error())
-	}
-
-	signedPreKey := publicKey[:]
-	signature := make([]byte, 64)
-	rand.Read(signature)
-
-	return publicKey[:], signedPreKey, signature
-}
-
 // createUserInDB saves a new user to the database
-func (s *AuthService) createUserInDB(req *CreateUserRequest, hashedPassword string, publicKey, signedPreKey, signature []byte) (*Users, error) {
+func (s *AuthService) createUserInDB(req *CreateUserRequest, hashedPassword sql.NullString) (*Users, error) {
 	userId := uuid.New()
 	now := time.Now()
 
 	query := `
         INSERT INTO users (
             id, phone_number, username, first_name, last_name, 
-            password_hash, public_key, signed_prekey, prekey_signature,
-            created_at, updated_at
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+            password_hash, phone_verified_at, created_at, updated_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
 	_, err := s.db.Exec(
 		query,
 		userId, req.PhoneNumber, req.Username, req.FirstName, req.LastName,
-		hashedPassword, publicKey, signedPreKey, signature, now, now,
+		hashedPassword, now, now, now,
 	)
 
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrAccountExists
+		}
 		return nil, err
 	}
 
@@ -150,23 +246,28 @@ func (s *AuthService) createUserInDB(req *CreateUserRequest, hashedPassword stri
 	}, nil
 }
 
-// generateJWT creates a JWT token for the user
-func (s *AuthService) generateJWT(userID uuid.UUID) (string, time.Time, error) {
-	// Token expires in 24 hours
-	expiresAt := time.Now().Add(24 * time.Hour)
+// generateJWT creates a JWT token for the user bound to a login session
+func (s *AuthService) generateJWT(userID uuid.UUID, sessionID uuid.UUID) (string, time.Time, error) {
+	// Token lifetime is driven by JWT_EXPIRY
+	expiresAt := time.Now().Add(s.accessTokenTTL())
+
+	generation, err := s.tokenGeneration(userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
 
 	// Create the claims (data inside the token)
 	claims := jwt.MapClaims{
 		"user_id": userID.String(),
-		"exp":     expiresAt.Unix(),  // Expiration time
-		"iat":     time.Now().Unix(), // Issued at time
+		"sid":     sessionID.String(),  // Session (refresh token family) ID
+		"jti":     uuid.New().String(), // Unique token ID for revocation
+		"gen":     generation,          // Logout-all generation, see isTokenRevoked
+		"exp":     expiresAt.Unix(),    // Expiration time
+		"iat":     time.Now().Unix(),   // Issued at time
 	}
 
-	// Create the token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign it with your secret key
-	tokenString, err := token.SignedString([]byte(s.config.JWTSecret))
+	// Sign it with the active key
+	tokenString, err := s.keys.Sign(claims)
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -184,130 +285,58 @@ func (s *AuthService) userToResponse(user *Users) UserResponse {
 		LastName:       user.LastName,
 		Bio:            user.Bio,
 		ProfilePhotoID: user.ProfilePhotoID,
+		AvatarURL:      user.AvatarURL,
 	}
 }
 
+// AvatarURLExpr returns a SQL expression for the small avatar URL of the
+// current profile photo of the users row aliased as alias
+func AvatarURLExpr(alias string) string {
+	return fmt.Sprintf(`(SELECT f.cdn_url FROM profile_photos pp JOIN files f ON f.id = pp.small_file_id
+		WHERE pp.id = %s.profile_photo_id)`, alias)
+}
+
 // findUserByCredentials finds a user by phone number or username
 func (s *AuthService) findUserByCredentials(req *LoginRequest) (*Users, error) {
 	var query string
 	var param string
 
+	columns := `id, phone_number, username, first_name, last_name, COALESCE(bio, ''),
+		profile_photo_id, ` + AvatarURLExpr("users") + `, password_hash, status, created_at`
+
 	// Decide whether to search by phone or username
 	if req.PhoneNumber != "" {
-		query = "SELECT id, phone_number, username, first_name, last_name, password_hash, created_at FROM users WHERE phone_number = $1"
+		query = "SELECT " + columns + " FROM users WHERE phone_number = $1 AND status != 'deleted'"
 		param = req.PhoneNumber
 	} else if req.Username != "" {
-		query = "SELECT id, phone_number, username, first_name, last_name, password_hash, created_at FROM users WHERE username = $1"
+		query = "SELECT " + columns + " FROM users WHERE username = $1 AND status != 'deleted'"
 		param = req.Username
 	} else {
-		return nil, errors.New("phone number or username required")
+		return nil, ErrMissingCredentials
 	}
 
 	var user Users
+	var passwordHash sql.NullString
+	var profilePhotoID uuid.NullUUID
+	var avatarURL sql.NullString
 	err := s.db.QueryRow(query, param).Scan(
 		&user.Id, &user.PhoneNumber, &user.Username,
-		&user.FirstName, &user.LastName, &user.PasswordHash, &user.CreatedAt,
+		&user.FirstName, &user.LastName, &user.Bio,
+		&profilePhotoID, &avatarURL, &passwordHash, &user.Status, &user.CreatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, errUserNotFound
 		}
 		return nil, err
 	}
 
-	return &user, nil
-}
-
-// Fixed version with better debugging
-func (s *AuthService) SearchUsers(query string, searchType string, currentUserID uuid.UUID) ([]UserSearchResult, error) {
-	fmt.Printf("🔍 Search Debug: query='%s', type='%s', userID='%s'\n", query, searchType, currentUserID)
-
-	var sqlQuery string
-	var args []interface{}
-
-	// Convert searchType to lowercase for consistency
-	searchType = strings.ToLower(searchType)
-
-	switch searchType {
-	case "username":
-		sqlQuery = `
-			SELECT id, username, first_name, last_name, phone_number, bio, is_public
-			FROM users 
-			WHERE username ILIKE $1 AND id != $2
-			ORDER BY username
-			LIMIT 20`
-		args = []interface{}{"%" + query + "%", currentUserID}
-
-	case "phone":
-		sqlQuery = `
-			SELECT id, username, first_name, last_name, phone_number, bio, is_public
-			FROM users 
-			WHERE phone_number = $1 AND id != $2 AND allow_phone_discovery = true
-			LIMIT 1`
-		args = []interface{}{query, currentUserID}
-
-	case "name":
-		sqlQuery = `
-			SELECT id, username, first_name, last_name, phone_number, bio, is_public
-			FROM users 
-			WHERE (first_name ILIKE $1 OR last_name ILIKE $1) 
-			AND id != $2 AND is_public = true
-			ORDER BY first_name, last_name
-			LIMIT 20`
-		args = []interface{}{"%" + query + "%", currentUserID}
-
-	default:
-		// Global search - search everything
-		sqlQuery = `
-			SELECT id, username, first_name, last_name, phone_number, bio, is_public
-			FROM users 
-			WHERE (username ILIKE $1 OR first_name ILIKE $1 OR last_name ILIKE $1)
-			AND id != $2
-			ORDER BY username, first_name
-			LIMIT 20`
-		args = []interface{}{"%" + query + "%", currentUserID}
-	}
-
-	fmt.Printf("🔍 SQL Query: %s\n", sqlQuery)
-	fmt.Printf("🔍 Args: %v\n", args)
-
-	rows, err := s.db.Query(sqlQuery, args...)
-	if err != nil {
-		fmt.Printf("❌ Query error: %v\n", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	var results []UserSearchResult
-	for rows.Next() {
-		var user UserSearchResult
-		var phoneNumber sql.NullString
-		var bio sql.NullString
-
-		err := rows.Scan(
-			&user.ID, &user.Username, &user.FirstName, &user.LastName,
-			&phoneNumber, &bio, &user.IsPublic,
-		)
-		if err != nil {
-			fmt.Printf("❌ Scan error: %v\n", err)
-			continue
-		}
+	user.PasswordHash = passwordHash.String
+	user.ProfilePhotoID = profilePhotoID.UUID
+	user.AvatarURL = avatarURL.String
 
-		if phoneNumber.Valid {
-			user.PhoneNumber = phoneNumber.String
-		}
-
-		if bio.Valid {
-			user.Bio = bio.String
-		}
-
-		results = append(results, user)
-		fmt.Printf("✅ Found user: %s (%s %s)\n", user.Username, user.FirstName, user.LastName)
-	}
-
-	fmt.Printf("🔍 Total results: %d\n", len(results))
-	return results, nil
+	return &user, nil
 }
 
 // Helper functions
@@ -317,18 +346,3 @@ func (s *AuthService) areUsersContacts(userID1, userID2 uuid.UUID) bool {
 	err := s.db.QueryRow(query, userID1, userID2).Scan(&exists)
 	return err == nil
 }
-
-func (s *AuthService) findExistingPrivateChat(userID1, userID2 uuid.UUID) (uuid.UUID, bool) {
-	query := `
-		SELECT c.id FROM chats c
-		JOIN chat_members cm1 ON c.id = cm1.chat_id
-		JOIN chat_members cm2 ON c.id = cm2.chat_id
-		WHERE c.type = 'private' 
-		AND cm1.user_id = $1 AND cm1.status = 'active'
-		AND cm2.user_id = $2 AND cm2.status = 'active'
-		LIMIT 1`
-
-	var chatID uuid.UUID
-	err := s.db.QueryRow(query, userID1, userID2).Scan(&chatID)
-	return chatID, err == nil
-}
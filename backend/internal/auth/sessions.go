@@ -0,0 +1,165 @@
+// internal/auth/sessions.go
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	revokedSessionKeyPrefix = "auth:revoked_session:" // + session ID, set on termination
+	sessionTouchKeyPrefix   = "auth:session_touch:"   // + session ID, throttles last-active writes
+	userTouchKeyPrefix      = "auth:user_touch:"      // + user ID, throttles last-seen writes
+
+	sessionTouchInterval = time.Minute
+	userTouchInterval    = 5 * time.Minute
+	maxDeviceNameLength  = 100
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionNotifier is implemented by the WebSocket hub so that terminated
+// sessions lose their live connections immediately
+type SessionNotifier interface {
+	DisconnectSession(sessionID uuid.UUID)
+	DisconnectUser(userID uuid.UUID)
+}
+
+// createSession records a new login for the user and returns its ID
+func (s *AuthService) createSession(db execer, userID uuid.UUID, device DeviceInfo) (uuid.UUID, error) {
+	sessionID := uuid.New()
+	now := time.Now()
+
+	// VARCHAR(100) counts characters, so truncate on runes
+	deviceName := strings.TrimSpace(strings.ToValidUTF8(device.DeviceName, ""))
+	if utf8.RuneCountInString(deviceName) > maxDeviceNameLength {
+		deviceName = string([]rune(deviceName)[:maxDeviceNameLength])
+	}
+
+	query := `
+		INSERT INTO user_sessions (id, user_id, device_name, user_agent, ip_address, created_at, last_active_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := db.Exec(query, sessionID, userID, deviceName, device.UserAgent, device.IPAddress, now, now)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return sessionID, nil
+}
+
+// ListSessions returns the user's active sessions, most recently used first
+func (s *AuthService) ListSessions(userID uuid.UUID, currentSessionID uuid.UUID) ([]Session, error) {
+	query := `
+		SELECT id, device_name, user_agent, ip_address, created_at, last_active_at
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_active_at DESC`
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		var deviceName, userAgent, ipAddress sql.NullString
+
+		err := rows.Scan(
+			&session.ID, &deviceName, &userAgent, &ipAddress,
+			&session.CreatedAt, &session.LastActiveAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		session.DeviceName = deviceName.String
+		session.UserAgent = userAgent.String
+		session.IPAddress = ipAddress.String
+		session.IsCurrent = session.ID == currentSessionID
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// TerminateSession revokes one of the user's sessions and drops its live connections
+func (s *AuthService) TerminateSession(userID uuid.UUID, sessionID uuid.UUID) error {
+	query := `
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	result, err := s.db.Exec(query, sessionID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return s.revokeSessionTokens(sessionID)
+}
+
+// revokeSessionTokens revokes everything issued to a session that has just
+// been marked revoked: refresh tokens, outstanding access tokens and sockets
+func (s *AuthService) revokeSessionTokens(sessionID uuid.UUID) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`
+	if _, err := s.db.Exec(query, sessionID); err != nil {
+		return err
+	}
+
+	// Access tokens cannot outlive the access TTL, so the marker can expire with them
+	ctx := context.Background()
+	if err := s.rdb.Set(ctx, revokedSessionKeyPrefix+sessionID.String(), 1, s.accessTokenTTL()).Err(); err != nil {
+		return err
+	}
+
+	if s.notifier != nil {
+		s.notifier.DisconnectSession(sessionID)
+	}
+
+	return nil
+}
+
+// TouchSession records session activity, writing at most once per interval
+func (s *AuthService) TouchSession(sessionID uuid.UUID) {
+	ctx := context.Background()
+
+	acquired, err := s.rdb.SetNX(ctx, sessionTouchKeyPrefix+sessionID.String(), 1, sessionTouchInterval).Result()
+	if err != nil || !acquired {
+		return
+	}
+
+	query := `UPDATE user_sessions SET last_active_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	if _, err := s.db.Exec(query, sessionID); err != nil {
+		log.Printf("Failed to update session activity: %v", err)
+	}
+}
+
+// TouchUser records API activity as the user's last seen time, writing at
+// most once per interval. Inactive accounts are deleted based on it.
+func (s *AuthService) TouchUser(userID uuid.UUID) {
+	ctx := context.Background()
+
+	acquired, err := s.rdb.SetNX(ctx, userTouchKeyPrefix+userID.String(), 1, userTouchInterval).Result()
+	if err != nil || !acquired {
+		return
+	}
+
+	query := `UPDATE users SET last_seen = NOW() WHERE id = $1`
+	if _, err := s.db.Exec(query, userID); err != nil {
+		log.Printf("Failed to update last seen: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// internal/auth/sms.go
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/atharva-navani16/chat-app.git/internal/config"
+)
+
+// SMSSender delivers text messages to phone numbers
+type SMSSender interface {
+	SendSMS(ctx context.Context, to string, body string) error
+}
+
+// NewSMSSender picks an SMS provider based on SMS_PROVIDER: "twilio", or
+// "log" to write messages to the server log during local development. Any
+// other value, including none, is an error so codes never end up in
+// production logs by accident.
+func NewSMSSender(cfg *config.Config) (SMSSender, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.SMSProvider)) {
+	case "twilio":
+		if cfg.TwilioAccountSID == "" || cfg.TwilioAuthToken == "" || cfg.TwilioPhoneNumber == "" {
+			return nil, errors.New("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_PHONE_NUMBER are required for the twilio SMS provider")
+		}
+		return NewTwilioSMSSender(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioPhoneNumber), nil
+	case "log":
+		log.Println("⚠️ SMS_PROVIDER is log, codes will be logged instead of sent")
+		return NewLogSMSSender(), nil
+	case "":
+		return nil, errors.New("SMS_PROVIDER is not set, use \"twilio\" or \"log\"")
+	default:
+		return nil, fmt.Errorf("unsupported SMS_PROVIDER %q", cfg.SMSProvider)
+	}
+}
+
+// TwilioSMSSender sends SMS through the Twilio Messages API
+type TwilioSMSSender struct {
+	accountSID string
+	authToken  string
+	fromNumber string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewTwilioSMSSender creates a Twilio-backed SMS sender
+func NewTwilioSMSSender(accountSID, authToken, fromNumber string) *TwilioSMSSender {
+	return &TwilioSMSSender{
+		accountSID: accountSID,
+		authToken:  authToken,
+		fromNumber: fromNumber,
+		baseURL:    "https://api.twilio.com/2010-04-01",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendSMS sends a single message via Twilio
+func (t *TwilioSMSSender) SendSMS(ctx context.Context, to string, body string) error {
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", t.baseURL, t.accountSID)
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", t.fromNumber)
+	form.Set("Body", body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("twilio returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+// LogSMSSender writes messages to the log. Intended for local development
+// only, as every code it sends ends up in the server log.
+type LogSMSSender struct{}
+
+// NewLogSMSSender creates a logging SMS sender
+func NewLogSMSSender() *LogSMSSender {
+	return &LogSMSSender{}
+}
+
+// SendSMS logs the message instead of sending it
+func (l *LogSMSSender) SendSMS(ctx context.Context, to string, body string) error {
+	log.Printf("📱 SMS to %s: %s", to, body)
+	return nil
+}
@@ -0,0 +1,239 @@
+// internal/auth/tokens.go
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/atharva-navani16/chat-app.git/internal/config"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAccessTokenTTL  = 24 * time.Hour
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// accessTokenTTL returns the access token lifetime configured by JWT_EXPIRY
+func (s *AuthService) accessTokenTTL() time.Duration {
+	return config.ParseDuration(s.config.JWTExpiry, defaultAccessTokenTTL)
+}
+
+// refreshTokenTTL returns the refresh token lifetime configured by JWT_REFRESH_EXPIRY
+func (s *AuthService) refreshTokenTTL() time.Duration {
+	return config.ParseDuration(s.config.JWTRefreshExpiry, defaultRefreshTokenTTL)
+}
+
+// issueTokens starts a new session for the user and issues its first token pair
+func (s *AuthService) issueTokens(user *Users, device DeviceInfo) (*AuthResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Signing in during the grace period cancels a pending deletion
+	if user.Status == UserStatusDeactivated {
+		if err := s.cancelAccountDeletion(tx, user.Id); err != nil {
+			return nil, err
+		}
+		user.Status = UserStatusActive
+	}
+
+	// The session ID doubles as the refresh token family ID
+	sessionID, err := s.createSession(tx, user.Id, device)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.createRefreshToken(tx, user.Id, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := s.generateJWT(user.Id, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+		User:         s.userToResponse(user),
+	}, nil
+}
+
+// RefreshTokens rotates a refresh token and returns a new token pair.
+// Presenting a token that was already rotated revokes its whole family.
+func (s *AuthService) RefreshTokens(rawToken string) (*AuthResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		tokenID    uuid.UUID
+		userID     uuid.UUID
+		familyID   uuid.UUID
+		replacedBy sql.NullString
+		revokedAt  sql.NullTime
+		expiresAt  time.Time
+	)
+
+	query := `
+		SELECT rt.id, rt.user_id, rt.family_id, rt.replaced_by,
+		       COALESCE(rt.revoked_at, us.revoked_at), rt.expires_at
+		FROM refresh_tokens rt
+		JOIN user_sessions us ON us.id = rt.family_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt`
+
+	err = tx.QueryRow(query, hashToken(rawToken)).Scan(
+		&tokenID, &userID, &familyID, &replacedBy, &revokedAt, &expiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	// A token that has already been exchanged is being replayed: assume it
+	// was stolen and terminate the session it belongs to.
+	if replacedBy.Valid {
+		if _, err := tx.Exec(`
+			UPDATE user_sessions SET revoked_at = NOW()
+			WHERE id = $1 AND revoked_at IS NULL`, familyID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		if err := s.revokeSessionTokens(familyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if revokedAt.Valid || time.Now().After(expiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Only a fresh sign-in can reactivate an account pending deletion
+	user, err := s.getUserForToken(tx, userID)
+	if err != nil || user.Status != UserStatusActive {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, err := s.createRefreshToken(tx, userID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE refresh_tokens
+		SET revoked_at = NOW(), replaced_by = (SELECT id FROM refresh_tokens WHERE token_hash = $2)
+		WHERE id = $1`, tokenID, hashToken(newRefreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`UPDATE user_sessions SET last_active_at = NOW() WHERE id = $1`, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	token, accessExpiresAt, err := s.generateJWT(userID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Token:        token,
+		RefreshToken: newRefreshToken,
+		ExpiresAt:    accessExpiresAt,
+		User:         s.userToResponse(user),
+	}, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// createRefreshToken stores a new hashed refresh token in the given family
+// and returns the raw token for the client
+func (s *AuthService) createRefreshToken(db execer, userID, familyID uuid.UUID) (string, error) {
+	rawToken, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	now := time.Now()
+	_, err = db.Exec(query, userID, familyID, hashToken(rawToken), now, now.Add(s.refreshTokenTTL()))
+	if err != nil {
+		return "", err
+	}
+
+	return rawToken, nil
+}
+
+// getUserForToken loads the user a refresh token belongs to. Accounts
+// pending deletion are included so that signing in can reactivate them.
+func (s *AuthService) getUserForToken(db execer, userID uuid.UUID) (*Users, error) {
+	query := `
+		SELECT id, phone_number, username, first_name, last_name, status, created_at
+		FROM users
+		WHERE id = $1 AND status IN ('active', 'deactivated')`
+
+	var user Users
+	err := db.QueryRow(query, userID).Scan(
+		&user.Id, &user.PhoneNumber, &user.Username,
+		&user.FirstName, &user.LastName, &user.Status, &user.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// generateOpaqueToken returns a URL-safe random token
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the SHA-256 hex digest used to store opaque tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,476 @@
+// internal/auth/totp.go
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// TOTP parameters (RFC 6238 defaults understood by every authenticator app)
+const (
+	totpDigits     = 6
+	totpPeriod     = 30 // seconds
+	totpSkewSteps  = 1  // accept codes from one step either side
+	totpSecretSize = 20
+
+	recoveryCodeCount = 10
+
+	twoFactorChallengeTTL         = 5 * time.Minute
+	twoFactorChallengeMaxAttempts = 5
+	twoFactorChallengeKeyPrefix   = "auth:2fa_challenge:" // + token hash
+
+	defaultTOTPIssuer = "ChatApp"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotSetUp       = errors.New("two-factor setup has not been started")
+	ErrInvalidChallenge        = errors.New("invalid or expired two-factor challenge")
+	ErrReauthenticationFailed  = errors.New("re-authentication failed")
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorRequiredError is returned by Login when the password (or SMS code)
+// was correct but the account still needs a TOTP or recovery code
+type TwoFactorRequiredError struct {
+	ChallengeToken string
+	ExpiresAt      time.Time
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+// completeLogin issues tokens, or a two-factor challenge if the account has
+// 2FA enabled. identifier is the login scope whose failures are cleared once
+// the login succeeds.
+func (s *AuthService) completeLogin(user *Users, identifier string, device DeviceInfo) (*AuthResponse, error) {
+	enabled, err := s.isTwoFactorEnabled(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		s.resetLoginFailures(identifier)
+		return s.issueTokens(user, device)
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	key := twoFactorChallengeKeyPrefix + hashToken(token)
+
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, key,
+		"user_id", user.Id.String(),
+		"identifier", identifier,
+		"device_name", device.DeviceName,
+		"user_agent", device.UserAgent,
+		"ip_address", device.IPAddress,
+		"attempts", 0,
+	)
+	pipe.Expire(ctx, key, twoFactorChallengeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return nil, &TwoFactorRequiredError{
+		ChallengeToken: token,
+		ExpiresAt:      time.Now().Add(twoFactorChallengeTTL),
+	}
+}
+
+// VerifyTwoFactor completes a login that returned a two-factor challenge.
+// Wrong codes count as failed logins against the account and the IP, so a
+// fresh challenge per password login does not give unlimited guesses.
+func (s *AuthService) VerifyTwoFactor(req *TwoFactorVerifyRequest) (*AuthResponse, error) {
+	ctx := context.Background()
+	key := twoFactorChallengeKeyPrefix + hashToken(req.ChallengeToken)
+
+	pipe := s.rdb.TxPipeline()
+	attemptsCmd := pipe.HIncrBy(ctx, key, "attempts", 1)
+	challengeCmd := pipe.HGetAll(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	challenge := challengeCmd.Val()
+	userID, err := uuid.Parse(challenge["user_id"])
+	if err != nil {
+		s.rdb.Del(ctx, key)
+		return nil, ErrInvalidChallenge
+	}
+
+	accountScope := twoFactorIdentifier(userID)
+	ipAddress := challenge["ip_address"]
+	if err := s.checkLoginAllowed(accountScope, ipAddress); err != nil {
+		s.rdb.Del(ctx, key)
+		return nil, err
+	}
+
+	if attemptsCmd.Val() > twoFactorChallengeMaxAttempts {
+		s.rdb.Del(ctx, key)
+		return nil, ErrTooManyAttempts
+	}
+
+	ok, err := s.checkSecondFactor(userID, req.Code, req.RecoveryCode)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.recordLoginFailure(accountScope, ipAddress)
+		return nil, ErrInvalidCode
+	}
+
+	// Challenges are single use
+	if deleted, _ := s.rdb.Del(ctx, key).Result(); deleted == 0 {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := s.getUserForToken(s.db, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+
+	s.resetLoginFailures(challenge["identifier"])
+	s.resetLoginFailures(accountScope)
+
+	return s.issueTokens(user, DeviceInfo{
+		DeviceName: challenge["device_name"],
+		UserAgent:  challenge["user_agent"],
+		IPAddress:  ipAddress,
+	})
+}
+
+// SetupTwoFactor starts TOTP enrollment and returns the secret to show the user
+func (s *AuthService) SetupTwoFactor(userID uuid.UUID) (*TwoFactorSetupResponse, error) {
+	enabled, err := s.isTwoFactorEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secretBytes := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, err
+	}
+	secret := base32NoPadding.EncodeToString(secretBytes)
+
+	// Restarting setup replaces any pending secret
+	query := `
+		INSERT INTO user_totp (user_id, secret, is_enabled, created_at)
+		VALUES ($1, $2, false, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = NULL, created_at = NOW()
+		WHERE user_totp.is_enabled = false`
+
+	if _, err := s.db.Exec(query, userID, secret); err != nil {
+		return nil, err
+	}
+
+	var username, phoneNumber sql.NullString
+	s.db.QueryRow(`SELECT username, phone_number FROM users WHERE id = $1`, userID).Scan(&username, &phoneNumber)
+	account := username.String
+	if account == "" {
+		account = phoneNumber.String
+	}
+
+	return &TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: s.otpAuthURI(secret, account),
+	}, nil
+}
+
+// EnableTwoFactor confirms enrollment with a code from the authenticator app
+// and returns a fresh set of recovery codes
+func (s *AuthService) EnableTwoFactor(userID uuid.UUID, code string) ([]string, error) {
+	var enabled bool
+	err := s.db.QueryRow(`SELECT is_enabled FROM user_totp WHERE user_id = $1`, userID).Scan(&enabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTwoFactorNotSetUp
+		}
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	ok, err := s.checkTOTP(userID, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE user_totp SET is_enabled = true, enabled_at = NOW() WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns 2FA off after re-authenticating the user
+func (s *AuthService) DisableTwoFactor(userID uuid.UUID, req *TwoFactorReauthRequest) error {
+	if err := s.reauthenticate(userID, req); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RegenerateRecoveryCodes invalidates existing recovery codes and issues new ones
+func (s *AuthService) RegenerateRecoveryCodes(userID uuid.UUID, req *TwoFactorReauthRequest) ([]string, error) {
+	if err := s.reauthenticate(userID, req); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	codes, err := s.replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// reauthenticate requires the account password (when one is set) together
+// with a current TOTP or recovery code
+func (s *AuthService) reauthenticate(userID uuid.UUID, req *TwoFactorReauthRequest) error {
+	enabled, err := s.isTwoFactorEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if err := s.checkCredentialAttempt(userID); err != nil {
+		return err
+	}
+
+	var passwordHash sql.NullString
+	if err := s.db.QueryRow(`SELECT password_hash FROM users WHERE id = $1`, userID).Scan(&passwordHash); err != nil {
+		return err
+	}
+	if passwordHash.Valid && passwordHash.String != "" && !s.checkPassword(req.Password, passwordHash.String) {
+		return ErrReauthenticationFailed
+	}
+
+	ok, err := s.checkSecondFactor(userID, req.Code, req.RecoveryCode)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrReauthenticationFailed
+	}
+
+	s.resetCredentialAttempts(userID)
+	return nil
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code
+func (s *AuthService) checkSecondFactor(userID uuid.UUID, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return s.useRecoveryCode(userID, recoveryCode)
+	}
+	if code != "" {
+		return s.checkTOTP(userID, code)
+	}
+	return false, nil
+}
+
+// checkTOTP validates a code against the user's secret, rejecting reuse of a
+// time step that has already been accepted
+func (s *AuthService) checkTOTP(userID uuid.UUID, code string) (bool, error) {
+	var secret string
+	var lastUsedStep sql.NullInt64
+	query := `SELECT secret, last_used_step FROM user_totp WHERE user_id = $1`
+	if err := s.db.QueryRow(query, userID).Scan(&secret, &lastUsedStep); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	key, err := base32NoPadding.DecodeString(secret)
+	if err != nil {
+		return false, fmt.Errorf("corrupt TOTP secret: %v", err)
+	}
+
+	currentStep := time.Now().Unix() / totpPeriod
+	for offset := int64(-totpSkewSteps); offset <= totpSkewSteps; offset++ {
+		step := currentStep + offset
+		if lastUsedStep.Valid && step <= lastUsedStep.Int64 {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) != 1 {
+			continue
+		}
+
+		// Claim the step atomically so a code cannot be used twice
+		result, err := s.db.Exec(`
+			UPDATE user_totp SET last_used_step = $2
+			WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)`, userID, step)
+		if err != nil {
+			return false, err
+		}
+		claimed, _ := result.RowsAffected()
+		return claimed == 1, nil
+	}
+
+	return false, nil
+}
+
+// useRecoveryCode marks a recovery code as used if it is valid
+func (s *AuthService) useRecoveryCode(userID uuid.UUID, code string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := s.db.Exec(query, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+
+	used, _ := result.RowsAffected()
+	return used == 1, nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores a new set
+func (s *AuthService) replaceRecoveryCodes(tx *sql.Tx, userID uuid.UUID) ([]string, error) {
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32NoPadding.EncodeToString(buf)) // 8 characters
+		code := raw[:4] + "-" + raw[4:]
+
+		query := `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+		if _, err := tx.Exec(query, userID, hashToken(normalizeRecoveryCode(code))); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// isTwoFactorEnabled reports whether the user has confirmed TOTP enrollment
+func (s *AuthService) isTwoFactorEnabled(userID uuid.UUID) (bool, error) {
+	var enabled bool
+	query := `SELECT EXISTS(SELECT 1 FROM user_totp WHERE user_id = $1 AND is_enabled = true)`
+	err := s.db.QueryRow(query, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// otpAuthURI builds the otpauth:// URI rendered as a QR code by clients
+func (s *AuthService) otpAuthURI(secret, account string) string {
+	issuer := s.config.TOTPIssuer
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// totpCode computes the RFC 6238 code for a time step
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,87 @@
+// internal/auth/usernames.go
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/atharva-navani16/chat-app.git/internal/config"
+	"github.com/google/uuid"
+)
+
+var ErrUsernameReserved = errors.New("username is not available")
+
+// defaultReservedUsernameWords are refused as username prefixes unless
+// RESERVED_USERNAMES overrides them, so nobody can pose as staff
+var defaultReservedUsernameWords = []string{
+	"admin", "administrator", "moderator", "official", "security", "staff", "support", "system",
+}
+
+// UsernamePolicy decides which usernames can be claimed at registration or
+// when changing username
+type UsernamePolicy struct {
+	db    *sql.DB
+	words []string
+}
+
+// NewUsernamePolicy loads the reserved word list from RESERVED_USERNAMES, a
+// comma-separated list of words a username may not start with
+func NewUsernamePolicy(db *sql.DB, cfg *config.Config) *UsernamePolicy {
+	words := defaultReservedUsernameWords
+	if strings.TrimSpace(cfg.ReservedUsernames) != "" {
+		words = nil
+		for _, word := range strings.Split(cfg.ReservedUsernames, ",") {
+			if word = normalizeUsername(word); word != "" {
+				words = append(words, word)
+			}
+		}
+	}
+
+	return &UsernamePolicy{
+		db:    db,
+		words: words,
+	}
+}
+
+// Check returns ErrUsernameReserved if the username starts with a reserved
+// word or was recently given up by another account. userID is the account
+// claiming it, or uuid.Nil at registration.
+func (p *UsernamePolicy) Check(username string, userID uuid.UUID) error {
+	normalized := normalizeUsername(username)
+	for _, word := range p.words {
+		if strings.HasPrefix(normalized, word) {
+			return ErrUsernameReserved
+		}
+	}
+
+	var held bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM username_history
+			WHERE LOWER(username) = LOWER($1) AND reserved_until > NOW() AND user_id != $2
+		)`
+	if err := p.db.QueryRow(query, username, userID).Scan(&held); err != nil {
+		return err
+	}
+	if held {
+		return ErrUsernameReserved
+	}
+
+	return nil
+}
+
+// isChatUsername reports whether a group or channel has the username,
+// ignoring case. Users and chats share one namespace.
+func isChatUsername(db execer, username string) (bool, error) {
+	var taken bool
+	query := `SELECT EXISTS(SELECT 1 FROM chats WHERE LOWER(username) = LOWER($1))`
+	err := db.QueryRow(query, username).Scan(&taken)
+	return taken, err
+}
+
+// normalizeUsername lowercases a username and drops underscores, so
+// "Sup_Port" matches the reserved word "support"
+func normalizeUsername(username string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(username)), "_", "")
+}
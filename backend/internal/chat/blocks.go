@@ -0,0 +1,89 @@
+// internal/chat/blocks.go
+package chat
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// ErrUserBlocked is returned when either side of a private chat has blocked
+// the other
+var ErrUserBlocked = errors.New("you cannot interact with this user")
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// isBlockedBetween reports whether either user has blocked the other
+func isBlockedBetween(db rowQuerier, userID1, userID2 uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM user_contacts
+			WHERE is_blocked = true
+			  AND ((user_id = $1 AND contact_user_id = $2) OR (user_id = $2 AND contact_user_id = $1))
+		)`
+
+	var blocked bool
+	err := db.QueryRow(query, userID1, userID2).Scan(&blocked)
+	return blocked, err
+}
+
+// checkPrivateChatBlock returns ErrUserBlocked if chatID is a private chat
+// whose other member has blocked, or been blocked by, the user. Group chats
+// are not affected by blocks.
+func (s *ChatService) checkPrivateChatBlock(userID uuid.UUID, chatID uuid.UUID) error {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM chats c
+			JOIN chat_members other ON other.chat_id = c.id AND other.user_id != $1
+			JOIN user_contacts uc ON uc.is_blocked = true
+			  AND ((uc.user_id = $1 AND uc.contact_user_id = other.user_id)
+			    OR (uc.user_id = other.user_id AND uc.contact_user_id = $1))
+			WHERE c.id = $2 AND c.type = 'private'
+		)`
+
+	var blocked bool
+	if err := s.db.QueryRow(query, userID, chatID).Scan(&blocked); err != nil {
+		return err
+	}
+	if blocked {
+		return ErrUserBlocked
+	}
+	return nil
+}
+
+// blockedUserSet returns everyone the user has blocked or been blocked by,
+// so their typing events can be withheld in both directions
+func (h *WSHub) blockedUserSet(userID uuid.UUID) map[uuid.UUID]bool {
+	if h.db == nil {
+		return nil
+	}
+
+	query := `
+		SELECT contact_user_id FROM user_contacts WHERE user_id = $1 AND is_blocked = true
+		UNION
+		SELECT user_id FROM user_contacts WHERE contact_user_id = $1 AND is_blocked = true`
+
+	rows, err := h.db.Query(query, userID)
+	if err != nil {
+		log.Printf("❌ Failed to load blocks for %s: %v", userID, err)
+		return nil
+	}
+	defer rows.Close()
+
+	blocked := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("❌ Failed to load blocks for %s: %v", userID, err)
+			return nil
+		}
+		blocked[id] = true
+	}
+
+	return blocked
+}
@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -39,6 +40,13 @@ func (h *ChatHandler) CreatePrivateChat(c *gin.Context) {
 
 	chatResponse, err := h.chatService.CreatePrivateChat(user.Id, &req)
 	if err != nil {
+		if errors.Is(err, ErrUserBlocked) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+				"code":  "USER_BLOCKED",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create chat",
 			"details": err.Error(),
@@ -139,7 +147,7 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 	message, err := h.chatService.SendMessage(user.Id, &req)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
-		if err.Error() == "permission denied" {
+		if err.Error() == "permission denied" || errors.Is(err, ErrUserBlocked) {
 			statusCode = http.StatusForbidden
 		}
 		c.JSON(statusCode, gin.H{
@@ -291,7 +299,7 @@ func (h *ChatHandler) GetChatMembers(c *gin.Context) {
 		return
 	}
 
-	members, err := h.chatService.getChatMembers(chatID)
+	members, err := h.chatService.getChatMembers(chatID, user.Id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get chat members",
@@ -310,9 +318,7 @@ func (h *ChatHandler) GetChatMembers(c *gin.Context) {
 	})
 }
 
-// Placeholder handlers for future implementation
-
-// UpdateChat updates chat details (title, description)
+// UpdateChat updates a group's or channel's info
 // PUT /api/v1/chats/:chat_id
 func (h *ChatHandler) UpdateChat(c *gin.Context) {
 	user, exists := auth.RequireUser(c)
@@ -329,14 +335,29 @@ func (h *ChatHandler) UpdateChat(c *gin.Context) {
 		return
 	}
 
+	var req UpdateChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	chatResponse, err := h.chatService.UpdateChat(user.Id, chatID, &req)
+	if err != nil {
+		respondChatError(c, err, "Failed to update chat")
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "Chat update functionality coming soon",
-		"chat_id":    chatID,
-		"updated_by": user.Username,
+		"message": "Chat updated successfully",
+		"data":    chatResponse,
 	})
 }
 
-// LeaveChat allows a user to leave a chat
+// LeaveChat allows a user to leave a chat, or delete a private chat. The
+// JSON body (LeaveChatRequest) is optional.
 // POST /api/v1/chats/:chat_id/leave
 func (h *ChatHandler) LeaveChat(c *gin.Context) {
 	user, exists := auth.RequireUser(c)
@@ -353,10 +374,28 @@ func (h *ChatHandler) LeaveChat(c *gin.Context) {
 		return
 	}
 
+	var req LeaveChatRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := h.chatService.LeaveChat(user.Id, chatID, &req); err != nil {
+		respondChatError(c, err, "Failed to leave chat")
+		return
+	}
+
+	message := "Left chat successfully"
+	if req.DeleteChat || req.ForBoth {
+		message = "Chat deleted successfully"
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Leave chat functionality coming soon",
-		"chat_id": chatID,
-		"user":    user.Username,
+		"message": message,
 	})
 }
 
@@ -377,14 +416,29 @@ func (h *ChatHandler) AddMembersToGroup(c *gin.Context) {
 		return
 	}
 
+	var req AddMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.chatService.AddMembers(user.Id, chatID, &req)
+	if err != nil {
+		respondChatError(c, err, "Failed to add members")
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Add members functionality coming soon",
-		"chat_id":  chatID,
-		"added_by": user.Username,
+		"message": fmt.Sprintf("Added %d member(s)", result.AddedCount),
+		"data":    result,
 	})
 }
 
-// RemoveMemberFromGroup removes a member from a group chat
+// RemoveMemberFromGroup removes a member from a group chat. With ?ban=true
+// the member is banned rather than kicked.
 // DELETE /api/v1/chats/:chat_id/members/:user_id
 func (h *ChatHandler) RemoveMemberFromGroup(c *gin.Context) {
 	user, exists := auth.RequireUser(c)
@@ -410,11 +464,25 @@ func (h *ChatHandler) RemoveMemberFromGroup(c *gin.Context) {
 		return
 	}
 
+	ban, err := strconv.ParseBool(c.DefaultQuery("ban", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ban parameter",
+		})
+		return
+	}
+
+	if err := h.chatService.RemoveMember(user.Id, chatID, memberID, ban); err != nil {
+		respondChatError(c, err, "Failed to remove member")
+		return
+	}
+
+	message := "Member removed successfully"
+	if ban {
+		message = "Member banned successfully"
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "Remove member functionality coming soon",
-		"chat_id":    chatID,
-		"member_id":  memberID,
-		"removed_by": user.Username,
+		"message": message,
 	})
 }
 
@@ -530,7 +598,7 @@ func (h *ChatHandler) AddReaction(c *gin.Context) {
 	reactionResponse, err := h.chatService.AddReaction(user.Id, messageID, chatID, req.ReactionType)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
-		if err.Error() == "access denied" {
+		if err.Error() == "access denied" || errors.Is(err, ErrUserBlocked) {
 			statusCode = http.StatusForbidden
 		}
 		c.JSON(statusCode, gin.H{
@@ -682,3 +750,39 @@ func (h *ChatHandler) ForwardMessages(c *gin.Context) {
 		"data": forwardResponse,
 	})
 }
+
+// respondChatError maps chat management errors to HTTP responses
+func respondChatError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrChatNotFound), errors.Is(err, ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrChatPermission), errors.Is(err, ErrUserBlocked):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotGroupChat), errors.Is(err, ErrCannotRemoveSelf),
+		errors.Is(err, ErrChatTitleRequired), errors.Is(err, ErrInvalidChatUsername),
+		errors.Is(err, ErrPublicChatUsername), errors.Is(err, ErrInvalidChatPhoto):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrChatUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "USERNAME_TAKEN",
+		})
+	case errors.Is(err, auth.ErrUsernameReserved):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "USERNAME_RESERVED",
+		})
+	case errors.Is(err, ErrOwnershipTransferRequired):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "OWNERSHIP_TRANSFER_REQUIRED",
+		})
+	case errors.Is(err, ErrMemberLimitReached):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "MEMBER_LIMIT_REACHED",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,255 @@
+// internal/chat/leave.go
+package chat
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrOwnershipTransferRequired = errors.New("the creator must transfer ownership or delete the chat before leaving")
+
+// LeaveChat takes the user out of a chat.
+//
+// In groups the member leaves and a service message says so. The creator
+// has to name a member in TransferTo to hand the group over, or set
+// DeleteChat to close it for everyone.
+//
+// Leaving a private chat deletes it for the user: their history is cleared
+// and the chat comes back, empty, when either side writes again. With
+// ForBoth the chat and its messages are deleted for both members.
+func (s *ChatService) LeaveChat(userID, chatID uuid.UUID, req *LeaveChatRequest) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	chat, err := lockChat(tx, chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	if chat.chatType == "private" {
+		if req.ForBoth {
+			return s.deleteChat(tx, chatID, userID)
+		}
+		return s.deletePrivateChatForUser(tx, chatID, userID)
+	}
+
+	if req.DeleteChat {
+		if chat.actorRole != "creator" {
+			return ErrChatPermission
+		}
+		return s.deleteChat(tx, chatID, userID)
+	}
+
+	var messages []*Message
+	if chat.actorRole == "creator" {
+		if req.TransferTo == nil || *req.TransferTo == userID {
+			return ErrOwnershipTransferRequired
+		}
+		message, err := s.transferOwnership(tx, chatID, userID, *req.TransferTo)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	} else if req.TransferTo != nil {
+		return ErrChatPermission
+	}
+
+	query := `
+		UPDATE chat_members SET status = 'left', left_at = NOW()
+		WHERE chat_id = $1 AND user_id = $2`
+	if _, err := tx.Exec(query, chatID, userID); err != nil {
+		return err
+	}
+
+	message, err := s.postServiceMessage(tx, chatID, userID, ServiceAction{
+		Action:  ServiceMemberLeft,
+		UserIDs: []uuid.UUID{userID},
+	})
+	if err != nil {
+		return err
+	}
+	messages = append(messages, message)
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.wsHub.UnsubscribeUsersFromChat(chatID, userID)
+	s.wsHub.InvalidatePresenceAudience(userID)
+	for _, message := range messages {
+		s.broadcastServiceMessage(message)
+	}
+	// The user's other devices drop the chat too
+	s.wsHub.SendToUsers([]uuid.UUID{userID}, WSMessage{
+		Type:   WSChatMemberRemoved,
+		ChatID: chatID,
+		UserID: userID,
+		Content: map[string]interface{}{
+			"chat_id": chatID,
+			"status":  "left",
+		},
+		Timestamp: time.Now(),
+	})
+
+	return nil
+}
+
+// transferOwnership makes newOwnerID, an active member, the creator of the
+// group and the previous creator an admin
+func (s *ChatService) transferOwnership(tx *sql.Tx, chatID, ownerID, newOwnerID uuid.UUID) (*Message, error) {
+	query := `
+		UPDATE chat_members SET role = 'creator'
+		WHERE chat_id = $1 AND user_id = $2 AND status = 'active'`
+	result, err := tx.Exec(query, chatID, newOwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if rows == 0 {
+		return nil, ErrMemberNotFound
+	}
+
+	query = `UPDATE chat_members SET role = 'admin' WHERE chat_id = $1 AND user_id = $2`
+	if _, err := tx.Exec(query, chatID, ownerID); err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE chats SET creator_id = $2 WHERE id = $1`
+	if _, err := tx.Exec(query, chatID, newOwnerID); err != nil {
+		return nil, err
+	}
+
+	return s.postServiceMessage(tx, chatID, ownerID, ServiceAction{
+		Action:  ServiceOwnerChanged,
+		UserIDs: []uuid.UUID{newOwnerID},
+	})
+}
+
+// deletePrivateChatForUser hides a private chat and its history from one
+// member and commits tx
+func (s *ChatService) deletePrivateChatForUser(tx *sql.Tx, chatID, userID uuid.UUID) error {
+	memberIDs, err := s.activeMemberIDs(chatID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE chat_members SET status = 'left', left_at = NOW(), cleared_at = NOW()
+		WHERE chat_id = $1 AND user_id = $2`
+	if _, err := tx.Exec(query, chatID, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.wsHub.UnsubscribeUsersFromChat(chatID, userID)
+	s.wsHub.InvalidatePresenceAudience(memberIDs...)
+	s.notifyChatDeleted(chatID, userID, false, []uuid.UUID{userID})
+	return nil
+}
+
+// deleteChat closes a chat for all of its members, deleting its messages,
+// and commits tx
+func (s *ChatService) deleteChat(tx *sql.Tx, chatID, userID uuid.UUID) error {
+	memberIDs, err := s.activeMemberIDs(chatID)
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`UPDATE chats SET is_active = false, updated_at = NOW() WHERE id = $1`,
+		`UPDATE chat_members SET status = 'left', left_at = NOW() WHERE chat_id = $1 AND status = 'active'`,
+		`UPDATE messages SET is_deleted = true, delete_for_everyone = true WHERE chat_id = $1 AND is_deleted = false`,
+	}
+	for _, query := range statements {
+		if _, err := tx.Exec(query, chatID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.wsHub.UnsubscribeUsersFromChat(chatID, memberIDs...)
+	s.wsHub.InvalidatePresenceAudience(memberIDs...)
+	s.notifyChatDeleted(chatID, userID, true, memberIDs)
+	return nil
+}
+
+// notifyChatDeleted tells users' devices to drop a chat from their chat list
+func (s *ChatService) notifyChatDeleted(chatID, deletedBy uuid.UUID, forEveryone bool, userIDs []uuid.UUID) {
+	s.wsHub.SendToUsers(userIDs, WSMessage{
+		Type:   WSChatDeleted,
+		ChatID: chatID,
+		UserID: deletedBy,
+		Content: map[string]interface{}{
+			"chat_id":      chatID,
+			"deleted_by":   deletedBy,
+			"for_everyone": forEveryone,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// restorePrivateChat brings a private chat back for a member who deleted it
+// for themselves. Their cleared history stays hidden. Deleted accounts, whose
+// memberships were also marked left, stay out.
+func (s *ChatService) restorePrivateChat(chatID uuid.UUID) {
+	// Most messages go to chats nobody deleted, so check before updating
+	var deleted bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM chat_members cm
+			JOIN chats c ON c.id = cm.chat_id
+			WHERE cm.chat_id = $1 AND c.type = 'private' AND cm.status = 'left' AND cm.cleared_at IS NOT NULL
+		)`
+	if err := s.db.QueryRow(query, chatID).Scan(&deleted); err != nil {
+		log.Printf("❌ Failed to check private chat %s: %v", chatID, err)
+		return
+	}
+	if !deleted {
+		return
+	}
+
+	query = `
+		UPDATE chat_members cm SET status = 'active', left_at = NULL
+		FROM chats c, users u
+		WHERE c.id = cm.chat_id AND c.id = $1 AND c.type = 'private' AND c.is_active = true
+		  AND cm.status = 'left' AND cm.cleared_at IS NOT NULL
+		  AND u.id = cm.user_id AND u.status = 'active'
+		RETURNING cm.user_id`
+
+	rows, err := s.db.Query(query, chatID)
+	if err != nil {
+		log.Printf("❌ Failed to restore private chat %s: %v", chatID, err)
+		return
+	}
+	defer rows.Close()
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err == nil {
+			userIDs = append(userIDs, id)
+		}
+	}
+
+	if len(userIDs) > 0 {
+		s.wsHub.SubscribeUsersToChat(chatID, userIDs...)
+		memberIDs, err := s.activeMemberIDs(chatID)
+		if err == nil {
+			s.wsHub.InvalidatePresenceAudience(memberIDs...)
+		}
+	}
+}
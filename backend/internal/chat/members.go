@@ -0,0 +1,390 @@
+// internal/chat/members.go
+package chat
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrChatNotFound       = errors.New("chat not found or access denied")
+	ErrNotGroupChat       = errors.New("this action is only available in groups")
+	ErrChatPermission     = errors.New("you do not have permission to do this in this chat")
+	ErrMemberLimitReached = errors.New("chat member limit reached")
+	ErrMemberNotFound     = errors.New("user is not a member of this chat")
+	ErrCannotRemoveSelf   = errors.New("use leave to remove yourself from a chat")
+)
+
+// defaultMemberLimit applies to chats without a member_limit
+const defaultMemberLimit = 200
+
+// Reasons a user could not be added to a group
+const (
+	addFailedNotFound      = "user not found"
+	addFailedAlreadyMember = "user is already a member"
+	addFailedBanned        = "user is banned from this chat; only the creator can add them back"
+	addFailedNotAllowed    = "cannot add user" // deliberately vague so blocks are not revealed
+)
+
+// lockedChat is a group locked for a membership change, with the role of the
+// member making the change
+type lockedChat struct {
+	chatType    string
+	memberLimit int
+	actorRole   string
+}
+
+// lockGroupChat locks a group for a membership change. Concurrent changes to
+// the same chat wait until tx ends, so member_limit cannot be overshot.
+func lockGroupChat(tx *sql.Tx, chatID, actorID uuid.UUID) (*lockedChat, error) {
+	chat, err := lockChat(tx, chatID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.chatType == "private" {
+		return nil, ErrNotGroupChat
+	}
+	return chat, nil
+}
+
+// lockChat locks a chat the actor is an active member of
+func lockChat(tx *sql.Tx, chatID, actorID uuid.UUID) (*lockedChat, error) {
+	var chat lockedChat
+	query := `
+		SELECT type, COALESCE(member_limit, $2) FROM chats
+		WHERE id = $1 AND is_active = true
+		FOR UPDATE`
+	if err := tx.QueryRow(query, chatID, defaultMemberLimit).Scan(&chat.chatType, &chat.memberLimit); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrChatNotFound
+		}
+		return nil, err
+	}
+
+	query = `SELECT role FROM chat_members WHERE chat_id = $1 AND user_id = $2 AND status = 'active'`
+	if err := tx.QueryRow(query, chatID, actorID).Scan(&chat.actorRole); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrChatNotFound
+		}
+		return nil, err
+	}
+
+	return &chat, nil
+}
+
+// isChatAdmin reports whether a member role can manage the chat
+func isChatAdmin(role string) bool {
+	return role == "creator" || role == "admin"
+}
+
+// canRemoveMember reports whether a member with actorRole may remove one with
+// targetRole. The creator can remove anyone else; admins can remove members.
+func canRemoveMember(actorRole, targetRole string) bool {
+	switch actorRole {
+	case "creator":
+		return targetRole != "creator"
+	case "admin":
+		return targetRole != "creator" && targetRole != "admin"
+	default:
+		return false
+	}
+}
+
+// AddMembers adds users to a group on behalf of its creator or an admin.
+// Users who left or were kicked can be added back; banned users only by the
+// creator, which lifts the ban. Users who cannot be added are listed in
+// FailedUsers rather than failing the whole request, but the request fails
+// if the rest would not fit within the chat's member_limit.
+func (s *ChatService) AddMembers(actorID, chatID uuid.UUID, req *AddMembersRequest) (*AddMembersResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chat, err := lockGroupChat(tx, chatID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !isChatAdmin(chat.actorRole) {
+		return nil, ErrChatPermission
+	}
+
+	response := &AddMembersResponse{Added: []ChatMember{}}
+	var added []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, userID := range req.UserIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		reason, err := s.checkNewMember(tx, chatID, actorID, chat.actorRole, userID)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			response.FailedUsers = append(response.FailedUsers, FailedMember{UserID: userID, Error: reason})
+			continue
+		}
+		added = append(added, userID)
+	}
+
+	if len(added) == 0 {
+		return response, nil
+	}
+
+	var memberCount int
+	query := `SELECT COUNT(*) FROM chat_members WHERE chat_id = $1 AND status = 'active'`
+	if err := tx.QueryRow(query, chatID).Scan(&memberCount); err != nil {
+		return nil, err
+	}
+	if memberCount+len(added) > chat.memberLimit {
+		return nil, ErrMemberLimitReached
+	}
+
+	query = `
+		INSERT INTO chat_members (chat_id, user_id, role, status, joined_at, invited_by)
+		VALUES ($1, $2, 'member', 'active', NOW(), $3)
+		ON CONFLICT (chat_id, user_id) DO UPDATE
+		SET role = 'member', status = 'active', joined_at = NOW(), left_at = NULL,
+		    invited_by = EXCLUDED.invited_by`
+	for _, userID := range added {
+		if _, err := tx.Exec(query, chatID, userID, actorID); err != nil {
+			return nil, err
+		}
+	}
+
+	message, err := s.postServiceMessage(tx, chatID, actorID, ServiceAction{
+		Action:  ServiceMembersAdded,
+		UserIDs: added,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.wsHub.SubscribeUsersToChat(chatID, added...)
+	s.broadcastServiceMessage(message)
+	s.wsHub.SendToUsers(added, WSMessage{
+		Type:   WSChatMembersAdded,
+		ChatID: chatID,
+		UserID: actorID,
+		Content: map[string]interface{}{
+			"chat_id":  chatID,
+			"added_by": actorID,
+			"user_ids": added,
+		},
+		Timestamp: time.Now(),
+	})
+
+	members, err := s.getChatMembers(chatID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	isAdded := make(map[uuid.UUID]bool, len(added))
+	for _, userID := range added {
+		isAdded[userID] = true
+	}
+	for _, member := range members {
+		if isAdded[member.UserID] {
+			response.Added = append(response.Added, member)
+		}
+	}
+	response.AddedCount = len(added)
+
+	return response, nil
+}
+
+// checkNewMember returns why a user cannot be added to a group, or "" if
+// they can be
+func (s *ChatService) checkNewMember(tx *sql.Tx, chatID, actorID uuid.UUID, actorRole string, userID uuid.UUID) (string, error) {
+	var active bool
+	var status string
+	query := `
+		SELECT u.status = 'active', COALESCE(cm.status, '')
+		FROM users u
+		LEFT JOIN chat_members cm ON cm.chat_id = $1 AND cm.user_id = u.id
+		WHERE u.id = $2`
+	if err := tx.QueryRow(query, chatID, userID).Scan(&active, &status); err != nil {
+		if err == sql.ErrNoRows {
+			return addFailedNotFound, nil
+		}
+		return "", err
+	}
+
+	switch {
+	case !active:
+		return addFailedNotFound, nil
+	case status == "active":
+		return addFailedAlreadyMember, nil
+	case status == "banned" && actorRole != "creator":
+		return addFailedBanned, nil
+	}
+
+	blocked, err := isBlockedBetween(tx, actorID, userID)
+	if err != nil {
+		return "", err
+	}
+	if blocked {
+		return addFailedNotAllowed, nil
+	}
+	return "", nil
+}
+
+// RemoveMember removes a member from a group. A kicked member can be added
+// back by any admin; a banned one only by the creator.
+func (s *ChatService) RemoveMember(actorID, chatID, memberID uuid.UUID, ban bool) error {
+	if memberID == actorID {
+		return ErrCannotRemoveSelf
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	chat, err := lockGroupChat(tx, chatID, actorID)
+	if err != nil {
+		return err
+	}
+
+	var targetRole string
+	query := `
+		SELECT role FROM chat_members
+		WHERE chat_id = $1 AND user_id = $2 AND status = 'active'
+		FOR UPDATE`
+	if err := tx.QueryRow(query, chatID, memberID).Scan(&targetRole); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	if !canRemoveMember(chat.actorRole, targetRole) {
+		return ErrChatPermission
+	}
+
+	status, action := "kicked", ServiceMemberKicked
+	if ban {
+		status, action = "banned", ServiceMemberBanned
+	}
+
+	query = `UPDATE chat_members SET status = $3, left_at = NOW() WHERE chat_id = $1 AND user_id = $2`
+	if _, err := tx.Exec(query, chatID, memberID, status); err != nil {
+		return err
+	}
+
+	message, err := s.postServiceMessage(tx, chatID, actorID, ServiceAction{
+		Action:  action,
+		UserIDs: []uuid.UUID{memberID},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.wsHub.UnsubscribeUsersFromChat(chatID, memberID)
+	s.broadcastServiceMessage(message)
+	s.wsHub.SendToUsers([]uuid.UUID{memberID}, WSMessage{
+		Type:   WSChatMemberRemoved,
+		ChatID: chatID,
+		UserID: actorID,
+		Content: map[string]interface{}{
+			"chat_id":    chatID,
+			"removed_by": actorID,
+			"status":     status,
+		},
+		Timestamp: time.Now(),
+	})
+
+	return nil
+}
+
+// postServiceMessage records a service message announcing a change to the
+// chat, sent in the name of the user who made it
+func (s *ChatService) postServiceMessage(tx *sql.Tx, chatID, actorID uuid.UUID, action ServiceAction) (*Message, error) {
+	content, err := json.Marshal(action)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &Message{
+		ID:          uuid.New(),
+		ChatID:      chatID,
+		SenderID:    actorID,
+		MessageType: "service",
+		Content:     string(content),
+		CreatedAt:   time.Now(),
+	}
+
+	query := `
+		INSERT INTO messages (id, chat_id, sender_id, message_type, content, is_edited, is_deleted, created_at)
+		VALUES ($1, $2, $3, 'service', $4, false, false, $5)`
+	if _, err := tx.Exec(query, message.ID, chatID, actorID, message.Content, message.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE chats SET updated_at = NOW() WHERE id = $1`, chatID); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// broadcastServiceMessage delivers a service message to the live connections
+// of every active member of its chat
+func (s *ChatService) broadcastServiceMessage(message *Message) {
+	if senderInfo, err := s.getUserInfo(message.SenderID); err == nil {
+		message.SenderUsername = senderInfo.Username
+		message.SenderName = fmt.Sprintf("%s %s", senderInfo.FirstName, senderInfo.LastName)
+	}
+
+	memberIDs, err := s.activeMemberIDs(message.ChatID)
+	if err != nil {
+		log.Printf("❌ Failed to load members of chat %s: %v", message.ChatID, err)
+		return
+	}
+
+	s.wsHub.SendToUsers(memberIDs, WSMessage{
+		Type:      WSMessageReceived,
+		ChatID:    message.ChatID,
+		UserID:    message.SenderID,
+		MessageID: message.ID,
+		Content:   message,
+		Timestamp: time.Now(),
+	})
+}
+
+// activeMemberIDs lists the users currently in a chat
+func (s *ChatService) activeMemberIDs(chatID uuid.UUID) ([]uuid.UUID, error) {
+	query := `SELECT user_id FROM chat_members WHERE chat_id = $1 AND status = 'active'`
+	rows, err := s.db.Query(query, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs, rows.Err()
+}
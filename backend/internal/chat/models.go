@@ -11,12 +11,16 @@ type Chat struct {
 	Type        string    `json:"type" db:"type"`
 	Title       string    `json:"title,omitempty" db:"title"`
 	Description string    `json:"description,omitempty" db:"description"`
+	Username    string    `json:"username,omitempty" db:"username"`
+	IsPublic    bool      `json:"is_public" db:"is_public"`
 	CreatorID   uuid.UUID `json:"creator_id" db:"creator_id"`
 	IsActive    bool      `json:"is_active" db:"is_active"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 
 	// Additional fields for response
+	PhotoID     *uuid.UUID   `json:"photo_id,omitempty"`
+	PhotoURL    string       `json:"photo_url,omitempty"`
 	LastMessage *Message     `json:"last_message,omitempty"`
 	UnreadCount int          `json:"unread_count,omitempty"`
 	MemberCount int          `json:"member_count,omitempty"`
@@ -33,10 +37,12 @@ type ChatMember struct {
 	LeftAt    *time.Time `json:"left_at,omitempty" db:"left_at"`
 	InvitedBy *uuid.UUID `json:"invited_by,omitempty" db:"invited_by"`
 
-	// User info for response
+	// User info for response, with the viewer's contact name if they saved one
 	Username  string `json:"username,omitempty"`
 	FirstName string `json:"first_name,omitempty"`
 	LastName  string `json:"last_name,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	IsContact bool   `json:"is_contact"`
 }
 
 // Message represents a chat message
@@ -103,6 +109,33 @@ type GetMessagesRequest struct {
 	BeforeID *uuid.UUID `json:"before_id,omitempty"` // for cursor-based pagination
 }
 
+// AddMembersRequest for adding users to a group
+type AddMembersRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids" binding:"required,min=1,max=50"`
+}
+
+// LeaveChatRequest for leaving a chat. The body is optional.
+type LeaveChatRequest struct {
+	// Groups: the creator must hand the chat over to another member or
+	// delete it for everyone
+	TransferTo *uuid.UUID `json:"transfer_to,omitempty"`
+	DeleteChat bool       `json:"delete_chat,omitempty"`
+
+	// Private chats: delete the chat for the other member too
+	ForBoth bool `json:"for_both,omitempty"`
+}
+
+// UpdateChatRequest for changing a group's or channel's info. Omitted
+// fields are left unchanged.
+type UpdateChatRequest struct {
+	Title       *string    `json:"title,omitempty" binding:"omitempty,max=255"`
+	Description *string    `json:"description,omitempty" binding:"omitempty,max=255"`
+	Username    *string    `json:"username,omitempty"` // "" removes the public username
+	PhotoID     *uuid.UUID `json:"photo_id,omitempty"`
+	RemovePhoto bool       `json:"remove_photo,omitempty"`
+	IsPublic    *bool      `json:"is_public,omitempty"`
+}
+
 // Response structs
 
 // ChatResponse represents chat data in API responses
@@ -127,6 +160,40 @@ type ChatListResponse struct {
 	TotalCount int    `json:"total_count"`
 }
 
+// AddMembersResponse lists who was added to a group and who was not
+type AddMembersResponse struct {
+	Added       []ChatMember   `json:"added"`
+	AddedCount  int            `json:"added_count"`
+	FailedUsers []FailedMember `json:"failed_users,omitempty"`
+}
+
+// FailedMember is a user who could not be added to a group
+type FailedMember struct {
+	UserID uuid.UUID `json:"user_id"`
+	Error  string    `json:"error"`
+}
+
+// Service message actions
+const (
+	ServiceMembersAdded = "members_added"
+	ServiceMemberKicked = "member_kicked"
+	ServiceMemberBanned = "member_banned"
+	ServiceMemberLeft   = "member_left"
+	ServiceOwnerChanged = "owner_changed"
+	ServiceTitleChanged = "title_changed"
+	ServicePhotoChanged = "photo_changed"
+	ServicePhotoRemoved = "photo_removed"
+)
+
+// ServiceAction is the content of a service message (message_type
+// "service"), stored as JSON so clients can render it in their own words
+type ServiceAction struct {
+	Action  string      `json:"action"`
+	UserIDs []uuid.UUID `json:"user_ids,omitempty"`
+	Title   string      `json:"title,omitempty"`
+	PhotoID *uuid.UUID  `json:"photo_id,omitempty"`
+}
+
 // WebSocket message types
 type WSMessageType string
 
@@ -139,6 +206,13 @@ const (
 	WSUserOffline     WSMessageType = "user_offline"
 	WSMessageRead     WSMessageType = "message_read"
 	WSMessageReaction WSMessageType = "message_reaction"
+	WSProfileUpdated  WSMessageType = "profile_updated"
+	WSContactsChanged WSMessageType = "contacts_changed"
+
+	WSChatMembersAdded  WSMessageType = "chat_members_added"
+	WSChatMemberRemoved WSMessageType = "chat_member_removed"
+	WSChatDeleted       WSMessageType = "chat_deleted"
+	WSChatUpdated       WSMessageType = "chat_updated"
 )
 
 // WSMessage represents WebSocket messages
@@ -149,6 +223,13 @@ type WSMessage struct {
 	MessageID uuid.UUID     `json:"message_id,omitempty"`
 	Content   interface{}   `json:"content,omitempty"`
 	Timestamp time.Time     `json:"timestamp"`
+
+	// Users who must not receive this event because of a block
+	excludedUsers map[uuid.UUID]bool
+
+	// Users a presence event is fanned out to, resolved before the event
+	// reaches the hub so no database lookup happens under the hub lock
+	recipients map[uuid.UUID]bool
 }
 type MessageReaction struct {
 	MessageID    uuid.UUID `json:"message_id" db:"message_id"`
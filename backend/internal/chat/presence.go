@@ -0,0 +1,439 @@
+// internal/chat/presence.go
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// presenceDebounce is how long a user who dropped their last connection
+	// has to reconnect before their contacts are told they went offline
+	presenceDebounce = 5 * time.Second
+
+	// presenceAudienceTTL bounds how stale a cached presence audience may be
+	presenceAudienceTTL = 2 * time.Minute
+
+	// Live connections are counted per server in Redis so that a user stays
+	// online while connected to any server. Counts of servers whose
+	// heartbeat has expired are ignored and cleaned up.
+	presenceConnectionsKeyPrefix = "presence:connections:" // + user ID, hash of server ID -> connections
+	presenceServerKeyPrefix      = "presence:server:"      // + server ID, set while the server is running
+	presenceServerTTL            = 30 * time.Second
+	presenceHeartbeatInterval    = 10 * time.Second
+	presenceSweepInterval        = time.Minute
+)
+
+// Presence values. Viewers allowed by last_seen_privacy get online or offline
+// with the exact last seen time; everyone else gets one of the coarse values.
+const (
+	PresenceOnline      = "online"
+	PresenceOffline     = "offline"
+	PresenceRecently    = "recently"
+	PresenceWithinWeek  = "within_week"
+	PresenceWithinMonth = "within_month"
+	PresenceLongAgo     = "long_ago"
+)
+
+// CoarsePresence describes when a user was last seen without revealing the
+// exact time. Users who are online now are shown as "recently".
+func CoarsePresence(lastSeen time.Time, isOnline bool) string {
+	if isOnline {
+		return PresenceRecently
+	}
+
+	away := time.Since(lastSeen)
+	switch {
+	case lastSeen.IsZero():
+		return PresenceLongAgo
+	case away <= 3*24*time.Hour:
+		return PresenceRecently
+	case away <= 7*24*time.Hour:
+		return PresenceWithinWeek
+	case away <= 30*24*time.Hour:
+		return PresenceWithinMonth
+	default:
+		return PresenceLongAgo
+	}
+}
+
+// updatePresence saves the user's online status and last seen time, then
+// tells their contacts
+func (h *WSHub) updatePresence(userID uuid.UUID, isOnline bool) {
+	if h.db != nil {
+		query := `UPDATE users SET is_online = $2, last_seen = NOW() WHERE id = $1`
+		if _, err := h.db.Exec(query, userID, isOnline); err != nil {
+			log.Printf("❌ Failed to update presence for %s: %v", userID, err)
+		}
+	}
+
+	h.broadcastUserStatus(userID, isOnline)
+}
+
+// broadcastUserStatus sends user online/offline status to the user's
+// presence audience. Only the part of it allowed by the user's
+// last_seen_privacy hears about transitions; everyone else keeps the coarse
+// status they get from the API, which a live event would give away.
+func (h *WSHub) broadcastUserStatus(userID uuid.UUID, isOnline bool) {
+	msgType := WSUserOnline
+	status := PresenceOnline
+	if !isOnline {
+		msgType = WSUserOffline
+		status = PresenceOffline
+	}
+
+	audience := h.getPresenceAudience(userID)
+	if !isOnline {
+		// The next time the user comes online the audience is loaded afresh
+		h.InvalidatePresenceAudience(userID)
+	}
+
+	recipients := audience.recipients
+	if !audience.everyone {
+		recipients = make(map[uuid.UUID]bool)
+		for recipientID := range audience.recipients {
+			if audience.exact[recipientID] {
+				recipients[recipientID] = true
+			}
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	wsMessage := WSMessage{
+		Type:   msgType,
+		UserID: userID,
+		Content: map[string]interface{}{
+			"user_id":   userID,
+			"status":    status,
+			"is_online": isOnline,
+			"last_seen": time.Now(),
+		},
+		Timestamp:  time.Now(),
+		recipients: recipients,
+	}
+
+	h.broadcast <- wsMessage
+}
+
+// scheduleOffline announces the user offline once presenceDebounce has passed
+// without them reconnecting. Callers hold the hub lock.
+func (h *WSHub) scheduleOffline(userID uuid.UUID) {
+	var timer *time.Timer
+	timer = time.AfterFunc(presenceDebounce, func() {
+		h.mutex.Lock()
+		current := h.pendingOffline[userID] == timer
+		if current {
+			delete(h.pendingOffline, userID)
+		}
+		online := len(h.clients[userID]) > 0
+		h.mutex.Unlock()
+
+		if !current || online {
+			return
+		}
+		// Still connected to another server
+		if connections, err := h.syncConnectionCount(userID); err == nil && connections > 0 {
+			return
+		}
+		h.updatePresence(userID, false)
+	})
+	h.pendingOffline[userID] = timer
+}
+
+// cancelPendingOffline drops the user's pending offline announcement and
+// reports whether there was one, in which case their contacts still see them
+// online. Callers hold the hub lock.
+func (h *WSHub) cancelPendingOffline(userID uuid.UUID) bool {
+	timer, pending := h.pendingOffline[userID]
+	if !pending {
+		return false
+	}
+	// A timer that already fired sees it is no longer pending and gives up
+	timer.Stop()
+	delete(h.pendingOffline, userID)
+	return true
+}
+
+// syncConnectionCount records how many connections the user has on this
+// server and returns their live connections across all servers
+func (h *WSHub) syncConnectionCount(userID uuid.UUID) (int64, error) {
+	h.mutex.RLock()
+	local := len(h.clients[userID])
+	h.mutex.RUnlock()
+
+	if h.redis == nil {
+		return int64(local), nil
+	}
+
+	ctx := context.Background()
+	key := presenceConnectionsKeyPrefix + userID.String()
+	var err error
+	if local > 0 {
+		err = h.redis.HSet(ctx, key, h.serverID, local).Err()
+	} else {
+		err = h.redis.HDel(ctx, key, h.serverID).Err()
+	}
+	if err != nil {
+		log.Printf("❌ Failed to record connections for %s: %v", userID, err)
+		return int64(local), err
+	}
+
+	return h.liveConnectionCount(userID)
+}
+
+// liveConnectionCount sums the user's connections on servers that are still
+// running, dropping counts left behind by servers that went away
+func (h *WSHub) liveConnectionCount(userID uuid.UUID) (int64, error) {
+	ctx := context.Background()
+	key := presenceConnectionsKeyPrefix + userID.String()
+
+	counts, err := h.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for serverID, value := range counts {
+		alive, err := h.redis.Exists(ctx, presenceServerKeyPrefix+serverID).Result()
+		if err != nil {
+			return 0, err
+		}
+		if alive == 0 {
+			h.redis.HDel(ctx, key, serverID)
+			continue
+		}
+		n, _ := strconv.ParseInt(value, 10, 64)
+		total += n
+	}
+
+	return total, nil
+}
+
+// RunPresenceMaintenance keeps this server's heartbeat alive and clears the
+// online flag of users whose connections are gone, e.g. because the server
+// holding them crashed. It runs until ctx is cancelled.
+func (h *WSHub) RunPresenceMaintenance(ctx context.Context) {
+	heartbeat := time.NewTicker(presenceHeartbeatInterval)
+	defer heartbeat.Stop()
+	sweep := time.NewTicker(presenceSweepInterval)
+	defer sweep.Stop()
+
+	h.refreshServerHeartbeat()
+	h.clearStalePresence()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			h.refreshServerHeartbeat()
+		case <-sweep.C:
+			h.clearStalePresence()
+		}
+	}
+}
+
+// refreshServerHeartbeat marks this server as running
+func (h *WSHub) refreshServerHeartbeat() {
+	if h.redis == nil {
+		return
+	}
+
+	ctx := context.Background()
+	if err := h.redis.Set(ctx, presenceServerKeyPrefix+h.serverID, 1, presenceServerTTL).Err(); err != nil {
+		log.Printf("❌ Failed to refresh presence heartbeat: %v", err)
+	}
+}
+
+// clearStalePresence marks users offline who are flagged online but have no
+// live connection on any server
+func (h *WSHub) clearStalePresence() {
+	if h.db == nil || h.redis == nil {
+		return
+	}
+
+	userIDs, err := h.queryUserSet(`SELECT id FROM users WHERE is_online = true`)
+	if err != nil {
+		log.Printf("❌ Failed to load online users: %v", err)
+		return
+	}
+
+	cleared := 0
+	for userID := range userIDs {
+		h.mutex.RLock()
+		_, pending := h.pendingOffline[userID]
+		local := len(h.clients[userID])
+		h.mutex.RUnlock()
+		if pending || local > 0 {
+			continue
+		}
+
+		connections, err := h.liveConnectionCount(userID)
+		if err != nil || connections > 0 {
+			continue
+		}
+
+		query := `UPDATE users SET is_online = false WHERE id = $1 AND is_online = true`
+		if _, err := h.db.Exec(query, userID); err != nil {
+			log.Printf("❌ Failed to clear presence for %s: %v", userID, err)
+			continue
+		}
+		h.broadcastUserStatus(userID, false)
+		cleared++
+	}
+
+	if cleared > 0 {
+		log.Printf("🧹 Marked %d disconnected user(s) offline", cleared)
+	}
+}
+
+// ReleasePresence withdraws this server's connections before it shuts down
+// and marks users offline who are not connected anywhere else
+func (h *WSHub) ReleasePresence() {
+	if h.redis == nil {
+		return
+	}
+
+	h.mutex.RLock()
+	userIDs := make([]uuid.UUID, 0, len(h.clients))
+	for userID := range h.clients {
+		userIDs = append(userIDs, userID)
+	}
+	h.mutex.RUnlock()
+
+	ctx := context.Background()
+	h.redis.Del(ctx, presenceServerKeyPrefix+h.serverID)
+
+	for _, userID := range userIDs {
+		h.redis.HDel(ctx, presenceConnectionsKeyPrefix+userID.String(), h.serverID)
+
+		connections, err := h.liveConnectionCount(userID)
+		if err != nil || connections > 0 || h.db == nil {
+			continue
+		}
+
+		query := `UPDATE users SET is_online = false, last_seen = NOW() WHERE id = $1`
+		if _, err := h.db.Exec(query, userID); err != nil {
+			log.Printf("❌ Failed to update presence for %s: %v", userID, err)
+		}
+	}
+}
+
+// presenceAudience is who hears about a user's presence changes: their
+// mutual contacts and private chat partners, minus anyone on either side of
+// a block. everyone and exact follow the user's last_seen_privacy.
+type presenceAudience struct {
+	recipients map[uuid.UUID]bool
+	everyone   bool
+	exact      map[uuid.UUID]bool
+	loadedAt   time.Time
+}
+
+// getPresenceAudience returns the user's cached presence audience, loading it
+// if it is missing or older than presenceAudienceTTL
+func (h *WSHub) getPresenceAudience(userID uuid.UUID) *presenceAudience {
+	h.presenceMutex.Lock()
+	audience, cached := h.presenceCache[userID]
+	h.presenceMutex.Unlock()
+	if cached && time.Since(audience.loadedAt) < presenceAudienceTTL {
+		return audience
+	}
+
+	audience, err := h.loadPresenceAudience(userID)
+	if err != nil {
+		log.Printf("❌ Failed to load presence audience for %s: %v", userID, err)
+		return &presenceAudience{}
+	}
+
+	h.presenceMutex.Lock()
+	h.presenceCache[userID] = audience
+	h.presenceMutex.Unlock()
+	return audience
+}
+
+// InvalidatePresenceAudience drops the cached presence audiences of the given
+// users. Call it when contacts, blocks or private chats between users change.
+func (h *WSHub) InvalidatePresenceAudience(userIDs ...uuid.UUID) {
+	h.presenceMutex.Lock()
+	defer h.presenceMutex.Unlock()
+
+	for _, userID := range userIDs {
+		delete(h.presenceCache, userID)
+	}
+}
+
+// loadPresenceAudience reads the user's presence audience from the database
+func (h *WSHub) loadPresenceAudience(userID uuid.UUID) (*presenceAudience, error) {
+	audience := &presenceAudience{loadedAt: time.Now()}
+	if h.db == nil {
+		return audience, nil
+	}
+
+	var privacy string
+	query := `SELECT COALESCE(last_seen_privacy, 'everyone') FROM users WHERE id = $1`
+	if err := h.db.QueryRow(query, userID).Scan(&privacy); err != nil {
+		if err == sql.ErrNoRows {
+			return audience, nil
+		}
+		return nil, err
+	}
+
+	query = `
+		SELECT contact_user_id FROM user_contacts
+		WHERE user_id = $1 AND is_mutual = true AND is_blocked = false
+		UNION
+		SELECT cm2.user_id FROM chats c
+		JOIN chat_members cm1 ON cm1.chat_id = c.id AND cm1.user_id = $1 AND cm1.status = 'active'
+		JOIN chat_members cm2 ON cm2.chat_id = c.id AND cm2.user_id != $1 AND cm2.status = 'active'
+		WHERE c.type = 'private'
+		EXCEPT
+		SELECT contact_user_id FROM user_contacts WHERE user_id = $1 AND is_blocked = true
+		EXCEPT
+		SELECT user_id FROM user_contacts WHERE contact_user_id = $1 AND is_blocked = true`
+	recipients, err := h.queryUserSet(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	audience.recipients = recipients
+
+	switch privacy {
+	case "everyone":
+		audience.everyone = true
+	case "contacts":
+		query = `
+			SELECT contact_user_id FROM user_contacts
+			WHERE user_id = $1 AND is_contact = true AND is_blocked = false`
+		if audience.exact, err = h.queryUserSet(query, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return audience, nil
+}
+
+// queryUserSet runs a query selecting user IDs and collects them into a set
+func (h *WSHub) queryUserSet(query string, args ...interface{}) (map[uuid.UUID]bool, error) {
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users[id] = true
+	}
+
+	return users, rows.Err()
+}
@@ -7,26 +7,30 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
 	"github.com/atharva-navani16/chat-app.git/internal/config"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
 type ChatService struct {
-	db     *sql.DB
-	redis  *redis.Client
-	config *config.Config
-	wsHub  *WSHub
+	db        *sql.DB
+	redis     *redis.Client
+	config    *config.Config
+	wsHub     *WSHub
+	usernames *auth.UsernamePolicy
 }
 
 func NewChatService(db *sql.DB, redis *redis.Client, config *config.Config, wsHub *WSHub) *ChatService {
 	return &ChatService{
-		db:     db,
-		redis:  redis,
-		config: config,
-		wsHub:  wsHub,
+		db:        db,
+		redis:     redis,
+		config:    config,
+		wsHub:     wsHub,
+		usernames: auth.NewUsernamePolicy(db, config),
 	}
 }
 
@@ -35,6 +39,14 @@ func (s *ChatService) CreatePrivateChat(userID uuid.UUID, req *CreatePrivateChat
 	// Get target user ID (by ID or username)
 	var targetUserID uuid.UUID
 	if req.UserID != uuid.Nil {
+		var active bool
+		query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND status = 'active')`
+		if err := s.db.QueryRow(query, req.UserID).Scan(&active); err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, errors.New("user not found")
+		}
 		targetUserID = req.UserID
 	} else if req.Username != "" {
 		var err error
@@ -46,12 +58,29 @@ func (s *ChatService) CreatePrivateChat(userID uuid.UUID, req *CreatePrivateChat
 		return nil, errors.New("either user_id or username required")
 	}
 
-	// Check if chat already exists
+	// Check if chat already exists, bringing it back if the user deleted it
+	// for themselves
 	existingChat, err := s.findPrivateChat(userID, targetUserID)
 	if err == nil {
+		query := `
+			UPDATE chat_members SET status = 'active', left_at = NULL
+			WHERE chat_id = $1 AND user_id = $2 AND status = 'left'`
+		if _, err := s.db.Exec(query, existingChat.ID, userID); err != nil {
+			return nil, err
+		}
+		s.wsHub.SubscribeUsersToChat(existingChat.ID, userID)
 		return s.getChatResponse(existingChat.ID, userID)
 	}
 
+	// Blocked users cannot start a conversation in either direction
+	blocked, err := isBlockedBetween(s.db, userID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrUserBlocked
+	}
+
 	// Create new private chat
 	chatID := uuid.New()
 	now := time.Now()
@@ -91,6 +120,8 @@ func (s *ChatService) CreatePrivateChat(userID uuid.UUID, req *CreatePrivateChat
 		return nil, err
 	}
 
+	s.wsHub.InvalidatePresenceAudience(userID, targetUserID)
+
 	return s.getChatResponse(chatID, userID)
 }
 
@@ -157,6 +188,12 @@ func (s *ChatService) SendMessage(userID uuid.UUID, req *SendMessageRequest) (*M
 	if !canSend {
 		return nil, errors.New("permission denied")
 	}
+	if err := s.checkPrivateChatBlock(userID, req.ChatID); err != nil {
+		return nil, err
+	}
+
+	// A private chat the other member deleted for themselves comes back
+	s.restorePrivateChat(req.ChatID)
 
 	// Create message
 	messageID := uuid.New()
@@ -237,14 +274,15 @@ func (s *ChatService) GetMessages(userID uuid.UUID, req *GetMessagesRequest) (*M
 	query := `
 		SELECT m.id, m.chat_id, m.sender_id, m.message_type, m.content,
 		       m.reply_to_message_id, m.file_id, m.is_edited, m.is_deleted, m.created_at, m.edited_at,
-		       u.username, u.first_name, u.last_name
+		       COALESCE(u.username, ''), u.first_name, COALESCE(u.last_name, '')
 		FROM messages m
 		JOIN users u ON m.sender_id = u.id
-		WHERE m.chat_id = $1 AND m.is_deleted = false
+		JOIN chat_members cm ON cm.chat_id = m.chat_id AND cm.user_id = $4
+		WHERE m.chat_id = $1 AND m.is_deleted = false ` + notClearedForMember + `
 		ORDER BY m.created_at DESC
 		LIMIT $2 OFFSET $3`
 
-	rows, err := s.db.Query(query, req.ChatID, limit+1, offset) // +1 to check if there are more
+	rows, err := s.db.Query(query, req.ChatID, limit+1, offset, userID) // +1 to check if there are more
 	if err != nil {
 		return nil, err
 	}
@@ -282,8 +320,11 @@ func (s *ChatService) GetMessages(userID uuid.UUID, req *GetMessagesRequest) (*M
 
 	// Get total count
 	var totalCount int
-	countQuery := `SELECT COUNT(*) FROM messages WHERE chat_id = $1 AND is_deleted = false`
-	s.db.QueryRow(countQuery, req.ChatID).Scan(&totalCount)
+	countQuery := `
+		SELECT COUNT(*) FROM messages m
+		JOIN chat_members cm ON cm.chat_id = m.chat_id AND cm.user_id = $2
+		WHERE m.chat_id = $1 AND m.is_deleted = false ` + notClearedForMember
+	s.db.QueryRow(countQuery, req.ChatID, userID).Scan(&totalCount)
 
 	return &MessagesResponse{
 		Messages:   messages,
@@ -332,9 +373,12 @@ func (s *ChatService) GetUserChats(userID uuid.UUID) (*ChatListResponse, error)
 		if description.Valid {
 			chat.Description = description.String
 		}
+		if chat.Type == "private" && chat.Title == "" {
+			chat.Title = s.getPrivateChatTitle(chat.ID, userID)
+		}
 
 		// Get last message
-		lastMessage, _ := s.getLastMessage(chat.ID)
+		lastMessage, _ := s.getLastMessage(userID, chat.ID)
 		chat.LastMessage = lastMessage
 
 		// Get unread count for this user
@@ -357,9 +401,9 @@ func (s *ChatService) findPrivateChat(userID1, userID2 uuid.UUID) (*Chat, error)
 		FROM chats c
 		JOIN chat_members cm1 ON c.id = cm1.chat_id
 		JOIN chat_members cm2 ON c.id = cm2.chat_id
-		WHERE c.type = 'private' 
-		AND cm1.user_id = $1 AND cm1.status = 'active'
-		AND cm2.user_id = $2 AND cm2.status = 'active'
+		WHERE c.type = 'private' AND c.is_active = true
+		AND cm1.user_id = $1 AND cm1.status IN ('active', 'left')
+		AND cm2.user_id = $2 AND cm2.status IN ('active', 'left')
 		LIMIT 1`
 
 	var chat Chat
@@ -372,33 +416,45 @@ func (s *ChatService) findPrivateChat(userID1, userID2 uuid.UUID) (*Chat, error)
 func (s *ChatService) getChatResponse(chatID uuid.UUID, userID uuid.UUID) (*ChatResponse, error) {
 	// Get chat details
 	query := `
-		SELECT c.id, c.type, c.title, c.description, c.creator_id, c.is_active, c.created_at, c.updated_at,
+		SELECT c.id, c.type, c.title, c.description, COALESCE(c.username, ''), COALESCE(c.is_public, false),
+		       c.photo_id, f.cdn_url, c.creator_id, c.is_active, c.created_at, c.updated_at,
 		       cm.role
 		FROM chats c
 		JOIN chat_members cm ON c.id = cm.chat_id
+		LEFT JOIN files f ON f.id = c.photo_id
 		WHERE c.id = $1 AND cm.user_id = $2 AND cm.status = 'active'`
 
 	var chat Chat
 	var userRole string
-	var title, description sql.NullString
+	var title, description, photoURL sql.NullString
+	var photoID uuid.NullUUID
 
 	err := s.db.QueryRow(query, chatID, userID).Scan(
-		&chat.ID, &chat.Type, &title, &description, &chat.CreatorID, &chat.IsActive,
+		&chat.ID, &chat.Type, &title, &description, &chat.Username, &chat.IsPublic,
+		&photoID, &photoURL, &chat.CreatorID, &chat.IsActive,
 		&chat.CreatedAt, &chat.UpdatedAt, &userRole,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if photoID.Valid {
+		chat.PhotoID = &photoID.UUID
+		chat.PhotoURL = photoURL.String
+	}
+
 	if title.Valid {
 		chat.Title = title.String
 	}
 	if description.Valid {
 		chat.Description = description.String
 	}
+	if chat.Type == "private" && chat.Title == "" {
+		chat.Title = s.getPrivateChatTitle(chat.ID, userID)
+	}
 
 	// Get members
-	members, _ := s.getChatMembers(chatID)
+	members, _ := s.getChatMembers(chatID, userID)
 	chat.Members = members
 	chat.MemberCount = len(members)
 
@@ -410,16 +466,20 @@ func (s *ChatService) getChatResponse(chatID uuid.UUID, userID uuid.UUID) (*Chat
 	}, nil
 }
 
-func (s *ChatService) getChatMembers(chatID uuid.UUID) ([]ChatMember, error) {
+// getChatMembers lists active members, named as the viewer saved them in
+// their contacts
+func (s *ChatService) getChatMembers(chatID uuid.UUID, viewerID uuid.UUID) ([]ChatMember, error) {
 	query := `
 		SELECT cm.chat_id, cm.user_id, cm.role, cm.status, cm.joined_at, cm.left_at, cm.invited_by,
-		       u.username, u.first_name, u.last_name
+		       COALESCE(u.username, ''), ` + contactNameColumns + `, ` + auth.AvatarURLExpr("u") + `,
+		       COALESCE(uc.is_contact, false)
 		FROM chat_members cm
 		JOIN users u ON cm.user_id = u.id
+		LEFT JOIN user_contacts uc ON uc.user_id = $2 AND uc.contact_user_id = u.id
 		WHERE cm.chat_id = $1 AND cm.status = 'active'
 		ORDER BY cm.joined_at`
 
-	rows, err := s.db.Query(query, chatID)
+	rows, err := s.db.Query(query, chatID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -430,15 +490,19 @@ func (s *ChatService) getChatMembers(chatID uuid.UUID) ([]ChatMember, error) {
 		var member ChatMember
 		var leftAt sql.NullTime
 		var invitedBy sql.NullString
+		var avatarURL sql.NullString
 
 		err := rows.Scan(
 			&member.ChatID, &member.UserID, &member.Role, &member.Status, &member.JoinedAt,
-			&leftAt, &invitedBy, &member.Username, &member.FirstName, &member.LastName,
+			&leftAt, &invitedBy, &member.Username, &member.FirstName, &member.LastName, &avatarURL,
+			&member.IsContact,
 		)
 		if err != nil {
 			continue
 		}
 
+		member.AvatarURL = avatarURL.String
+
 		if leftAt.Valid {
 			member.LeftAt = &leftAt.Time
 		}
@@ -476,7 +540,7 @@ func (s *ChatService) isUserChatMember(userID uuid.UUID, chatID uuid.UUID) (bool
 }
 
 func (s *ChatService) getUserIDByUsername(username string) (uuid.UUID, error) {
-	query := `SELECT id FROM users WHERE username = $1`
+	query := `SELECT id FROM users WHERE username = $1 AND status = 'active'`
 	var userID uuid.UUID
 	err := s.db.QueryRow(query, username).Scan(&userID)
 	return userID, err
@@ -487,7 +551,7 @@ func (s *ChatService) getUserInfo(userID uuid.UUID) (*struct {
 	FirstName string
 	LastName  string
 }, error) {
-	query := `SELECT username, first_name, last_name FROM users WHERE id = $1`
+	query := `SELECT COALESCE(username, ''), first_name, COALESCE(last_name, '') FROM users WHERE id = $1`
 	var user struct {
 		Username  string
 		FirstName string
@@ -497,16 +561,46 @@ func (s *ChatService) getUserInfo(userID uuid.UUID) (*struct {
 	return &user, err
 }
 
-func (s *ChatService) getLastMessage(chatID uuid.UUID) (*Message, error) {
+// notClearedForMember hides messages from before the time chat_members row
+// cm cleared its history
+const notClearedForMember = `AND m.created_at > COALESCE(cm.cleared_at, '-infinity'::timestamp)`
+
+// contactNameColumns selects the first and last name of users row u, using
+// the name from the viewer's contact entry uc when one was saved
+const contactNameColumns = `
+		CASE WHEN COALESCE(uc.first_name, '') != '' THEN uc.first_name ELSE u.first_name END,
+		CASE WHEN COALESCE(uc.first_name, '') != '' THEN COALESCE(uc.last_name, '') ELSE COALESCE(u.last_name, '') END`
+
+// getPrivateChatTitle names a private chat after the other member, as the
+// viewer saved them in their contacts
+func (s *ChatService) getPrivateChatTitle(chatID uuid.UUID, viewerID uuid.UUID) string {
 	query := `
-		SELECT id, sender_id, message_type, content, created_at
-		FROM messages
-		WHERE chat_id = $1 AND is_deleted = false
-		ORDER BY created_at DESC
+		SELECT ` + contactNameColumns + `
+		FROM chat_members cm
+		JOIN users u ON cm.user_id = u.id
+		LEFT JOIN user_contacts uc ON uc.user_id = $2 AND uc.contact_user_id = u.id
+		WHERE cm.chat_id = $1 AND cm.user_id != $2
+		LIMIT 1`
+
+	var firstName, lastName string
+	if err := s.db.QueryRow(query, chatID, viewerID).Scan(&firstName, &lastName); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(firstName + " " + lastName)
+}
+
+// getLastMessage returns the latest message of a chat the user can still see
+func (s *ChatService) getLastMessage(userID, chatID uuid.UUID) (*Message, error) {
+	query := `
+		SELECT m.id, m.sender_id, m.message_type, m.content, m.created_at
+		FROM messages m
+		JOIN chat_members cm ON cm.chat_id = m.chat_id AND cm.user_id = $2
+		WHERE m.chat_id = $1 AND m.is_deleted = false ` + notClearedForMember + `
+		ORDER BY m.created_at DESC
 		LIMIT 1`
 
 	var msg Message
-	err := s.db.QueryRow(query, chatID).Scan(
+	err := s.db.QueryRow(query, chatID, userID).Scan(
 		&msg.ID, &msg.SenderID, &msg.MessageType, &msg.Content, &msg.CreatedAt,
 	)
 	return &msg, err
@@ -515,8 +609,9 @@ func (s *ChatService) getLastMessage(chatID uuid.UUID) (*Message, error) {
 func (s *ChatService) getUnreadCount(userID uuid.UUID, chatID uuid.UUID) int {
 	query := `
 		SELECT COUNT(*) FROM messages m
+		JOIN chat_members cm ON cm.chat_id = m.chat_id AND cm.user_id = $1
 		LEFT JOIN message_delivery md ON m.id = md.message_id AND md.user_id = $1
-		WHERE m.chat_id = $2 AND m.sender_id != $1 AND m.is_deleted = false
+		WHERE m.chat_id = $2 AND m.sender_id != $1 AND m.is_deleted = false ` + notClearedForMember + `
 		AND (md.status IS NULL OR md.status != 'read')`
 
 	var count int
@@ -548,6 +643,9 @@ func (s *ChatService) AddReaction(userID uuid.UUID, messageID uuid.UUID, chatID
 	if msgChatID != chatID {
 		return nil, errors.New("message not in this chat")
 	}
+	if err := s.checkPrivateChatBlock(userID, chatID); err != nil {
+		return nil, err
+	}
 
 	// Add or update reaction (upsert)
 	query := `
@@ -745,6 +843,9 @@ func (s *ChatService) forwardSingleMessage(userID uuid.UUID, messageID uuid.UUID
 	if err != nil || !canSend {
 		return nil, errors.New("cannot send to target chat")
 	}
+	if err := s.checkPrivateChatBlock(userID, toChatID); err != nil {
+		return nil, err
+	}
 
 	// 3. Check forward chain depth (prevent infinite forwarding)
 	depth := s.getForwardChainDepth(messageID)
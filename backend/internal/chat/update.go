@@ -0,0 +1,288 @@
+// internal/chat/update.go
+package chat
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrChatTitleRequired   = errors.New("title cannot be empty")
+	ErrInvalidChatUsername = errors.New("username must be 5-32 letters, digits or underscores")
+	ErrChatUsernameTaken   = errors.New("username is already taken")
+	ErrPublicChatUsername  = errors.New("public chats need a username")
+	ErrInvalidChatPhoto    = errors.New("photo must be an image you uploaded")
+)
+
+// chatUsernamePattern mirrors the valid_chat_username constraint on chats
+var chatUsernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]{5,32}$`)
+
+// chatInfo is the part of a chat UpdateChat can change
+type chatInfo struct {
+	title       string
+	description string
+	username    string
+	photoID     uuid.NullUUID
+	isPublic    bool
+}
+
+// UpdateChat changes a group's or channel's info on behalf of its creator or
+// an admin allowed to change it. Title and photo changes are announced with
+// a service message; every change is pushed to members' open clients.
+func (s *ChatService) UpdateChat(actorID, chatID uuid.UUID, req *UpdateChatRequest) (*ChatResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chat, err := lockGroupChat(tx, chatID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := canChangeChatInfo(tx, chatID, actorID, chat.actorRole)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrChatPermission
+	}
+
+	var current chatInfo
+	query := `
+		SELECT COALESCE(title, ''), COALESCE(description, ''), COALESCE(username, ''),
+		       photo_id, COALESCE(is_public, false)
+		FROM chats WHERE id = $1`
+	err = tx.QueryRow(query, chatID).Scan(
+		&current.title, &current.description, &current.username, &current.photoID, &current.isPublic,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.applyChatChanges(tx, chatID, actorID, current, req)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := changedChatFields(current, updated)
+	if len(changed) == 0 {
+		return s.getChatResponse(chatID, actorID)
+	}
+
+	query = `
+		UPDATE chats
+		SET title = $2, description = NULLIF($3, ''), username = NULLIF($4, ''),
+		    photo_id = $5, is_public = $6, updated_at = NOW()
+		WHERE id = $1`
+	_, err = tx.Exec(query, chatID, updated.title, updated.description, updated.username,
+		updated.photoID, updated.isPublic)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch {
+			case pqErr.Code == "23505":
+				return nil, ErrChatUsernameTaken
+			case pqErr.Code == "23514" && pqErr.Constraint == "valid_chat_username":
+				return nil, ErrInvalidChatUsername
+			}
+		}
+		return nil, err
+	}
+
+	var messages []*Message
+	for _, action := range chatServiceActions(current, updated) {
+		message, err := s.postServiceMessage(tx, chatID, actorID, action)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("✏️ Chat %s updated by %s: %s", chatID, actorID, strings.Join(changed, ", "))
+
+	for _, message := range messages {
+		s.broadcastServiceMessage(message)
+	}
+	s.broadcastChatUpdated(chatID, actorID, updated, changed)
+
+	return s.getChatResponse(chatID, actorID)
+}
+
+// canChangeChatInfo reports whether a member may change the chat's info.
+// Admins may unless their can_change_info permission was taken away.
+func canChangeChatInfo(tx *sql.Tx, chatID, userID uuid.UUID, role string) (bool, error) {
+	switch role {
+	case "creator":
+		return true, nil
+	case "admin":
+		var allowed bool
+		query := `
+			SELECT COALESCE((permissions->>'can_change_info')::boolean, true)
+			FROM chat_members WHERE chat_id = $1 AND user_id = $2`
+		err := tx.QueryRow(query, chatID, userID).Scan(&allowed)
+		return allowed, err
+	default:
+		return false, nil
+	}
+}
+
+// applyChatChanges validates the request and returns the chat info it asks for
+func (s *ChatService) applyChatChanges(tx *sql.Tx, chatID, actorID uuid.UUID, info chatInfo, req *UpdateChatRequest) (chatInfo, error) {
+	if req.Title != nil {
+		info.title = strings.TrimSpace(*req.Title)
+		if info.title == "" {
+			return info, ErrChatTitleRequired
+		}
+	}
+
+	if req.Description != nil {
+		info.description = strings.TrimSpace(*req.Description)
+	}
+
+	if req.Username != nil {
+		previous := info.username
+		info.username = strings.TrimPrefix(strings.TrimSpace(*req.Username), "@")
+		if info.username != "" && info.username != previous {
+			if !chatUsernamePattern.MatchString(info.username) {
+				return info, ErrInvalidChatUsername
+			}
+			// Reserved words and usernames recently given up by users are
+			// off limits to chats too, so channels cannot impersonate them
+			if err := s.usernames.Check(info.username, uuid.Nil); err != nil {
+				return info, err
+			}
+			taken, err := isChatUsernameTaken(tx, chatID, info.username)
+			if err != nil {
+				return info, err
+			}
+			if taken {
+				return info, ErrChatUsernameTaken
+			}
+		}
+	}
+
+	switch {
+	case req.RemovePhoto:
+		info.photoID = uuid.NullUUID{}
+	case req.PhotoID != nil:
+		var valid bool
+		query := `
+			SELECT EXISTS(
+				SELECT 1 FROM files
+				WHERE id = $1 AND uploaded_by = $2 AND file_type = 'image'
+			)`
+		if err := tx.QueryRow(query, *req.PhotoID, actorID).Scan(&valid); err != nil {
+			return info, err
+		}
+		if !valid {
+			return info, ErrInvalidChatPhoto
+		}
+		info.photoID = uuid.NullUUID{UUID: *req.PhotoID, Valid: true}
+	}
+
+	if req.IsPublic != nil {
+		info.isPublic = *req.IsPublic
+	}
+	if info.isPublic && info.username == "" {
+		return info, ErrPublicChatUsername
+	}
+
+	return info, nil
+}
+
+// isChatUsernameTaken reports whether another chat or a user already has the
+// username, ignoring case. Chats and users share one namespace.
+func isChatUsernameTaken(tx *sql.Tx, chatID uuid.UUID, username string) (bool, error) {
+	query := `
+		SELECT EXISTS(SELECT 1 FROM chats WHERE LOWER(username) = LOWER($2) AND id != $1)
+		    OR EXISTS(SELECT 1 FROM users WHERE LOWER(username) = LOWER($2))`
+
+	var taken bool
+	err := tx.QueryRow(query, chatID, username).Scan(&taken)
+	return taken, err
+}
+
+// changedChatFields names the fields that differ between before and after
+func changedChatFields(before, after chatInfo) []string {
+	var changed []string
+	if before.title != after.title {
+		changed = append(changed, "title")
+	}
+	if before.description != after.description {
+		changed = append(changed, "description")
+	}
+	if before.username != after.username {
+		changed = append(changed, "username")
+	}
+	if before.photoID != after.photoID {
+		changed = append(changed, "photo")
+	}
+	if before.isPublic != after.isPublic {
+		changed = append(changed, "is_public")
+	}
+	return changed
+}
+
+// chatServiceActions returns the service messages announcing the changes
+// members see in the chat header
+func chatServiceActions(before, after chatInfo) []ServiceAction {
+	var actions []ServiceAction
+	if before.title != after.title {
+		actions = append(actions, ServiceAction{Action: ServiceTitleChanged, Title: after.title})
+	}
+	if before.photoID != after.photoID {
+		if after.photoID.Valid {
+			photoID := after.photoID.UUID
+			actions = append(actions, ServiceAction{Action: ServicePhotoChanged, PhotoID: &photoID})
+		} else {
+			actions = append(actions, ServiceAction{Action: ServicePhotoRemoved})
+		}
+	}
+	return actions
+}
+
+// broadcastChatUpdated pushes a chat's new info to its members' open clients
+// so they can refresh the chat header
+func (s *ChatService) broadcastChatUpdated(chatID, actorID uuid.UUID, info chatInfo, changed []string) {
+	memberIDs, err := s.activeMemberIDs(chatID)
+	if err != nil {
+		log.Printf("❌ Failed to load members of chat %s: %v", chatID, err)
+		return
+	}
+
+	content := map[string]interface{}{
+		"chat_id":     chatID,
+		"updated_by":  actorID,
+		"changed":     changed,
+		"title":       info.title,
+		"description": info.description,
+		"username":    info.username,
+		"is_public":   info.isPublic,
+	}
+	if info.photoID.Valid {
+		var photoURL sql.NullString
+		s.db.QueryRow(`SELECT cdn_url FROM files WHERE id = $1`, info.photoID.UUID).Scan(&photoURL)
+		content["photo_id"] = info.photoID.UUID
+		content["photo_url"] = photoURL.String
+	}
+
+	s.wsHub.SendToUsers(memberIDs, WSMessage{
+		Type:      WSChatUpdated,
+		ChatID:    chatID,
+		UserID:    actorID,
+		Content:   content,
+		Timestamp: time.Now(),
+	})
+}
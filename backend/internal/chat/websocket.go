@@ -3,6 +3,7 @@ package chat
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -28,12 +29,13 @@ var upgrader = websocket.Upgrader{
 
 // WSClient represents a WebSocket client connection
 type WSClient struct {
-	ID       string
-	UserID   uuid.UUID
-	Username string
-	Conn     *websocket.Conn
-	Send     chan WSMessage
-	Hub      *WSHub
+	ID        string
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	Username  string
+	Conn      *websocket.Conn
+	Send      chan WSMessage
+	Hub       *WSHub
 
 	// Connection metadata
 	ConnectedAt time.Time
@@ -58,12 +60,25 @@ type WSHub struct {
 	// Redis for cross-server communication
 	redis *redis.Client
 
+	// Identifies this server's connection counts in Redis
+	serverID string
+
+	// Database for block lookups and presence updates
+	db *sql.DB
+
+	// Offline announcements waiting out presenceDebounce, guarded by mutex
+	pendingOffline map[uuid.UUID]*time.Timer
+
+	// Presence audiences by user, guarded by presenceMutex
+	presenceCache map[uuid.UUID]*presenceAudience
+	presenceMutex sync.Mutex
+
 	// Mutex for thread safety
 	mutex sync.RWMutex
 }
 
 // NewWSHub creates a new WebSocket hub
-func NewWSHub(redisClient *redis.Client) *WSHub {
+func NewWSHub(redisClient *redis.Client, db *sql.DB) *WSHub {
 	return &WSHub{
 		clients:    make(map[uuid.UUID]map[string]*WSClient),
 		chatRooms:  make(map[uuid.UUID]map[uuid.UUID]map[string]*WSClient),
@@ -71,6 +86,11 @@ func NewWSHub(redisClient *redis.Client) *WSHub {
 		unregister: make(chan *WSClient),
 		broadcast:  make(chan WSMessage, 256),
 		redis:      redisClient,
+		db:         db,
+		serverID:   uuid.NewString(),
+
+		pendingOffline: make(map[uuid.UUID]*time.Timer),
+		presenceCache:  make(map[uuid.UUID]*presenceAudience),
 	}
 }
 
@@ -108,10 +128,13 @@ func (h *WSHub) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	sessionID, _ := auth.GetCurrentSessionID(c)
+
 	// Create client
 	client := &WSClient{
 		ID:          generateClientID(),
 		UserID:      user.Id,
+		SessionID:   sessionID,
 		Username:    user.Username,
 		Conn:        conn,
 		Send:        make(chan WSMessage, 256),
@@ -135,6 +158,7 @@ func (h *WSHub) registerClient(client *WSClient) {
 	defer h.mutex.Unlock()
 
 	// Add to clients map
+	firstConnection := len(h.clients[client.UserID]) == 0
 	if h.clients[client.UserID] == nil {
 		h.clients[client.UserID] = make(map[string]*WSClient)
 	}
@@ -142,8 +166,17 @@ func (h *WSHub) registerClient(client *WSClient) {
 
 	log.Printf("✅ Client connected: %s (User: %s)", client.ID, client.Username)
 
-	// Send user online status to their contacts
-	h.broadcastUserStatus(client.UserID, true)
+	// Count the connection in Redis, then save and send user online status
+	// to their contacts unless they are back before their offline status
+	// went out. Runs in the background because Redis and database lookups
+	// must not hold the hub lock.
+	announce := firstConnection && !h.cancelPendingOffline(client.UserID)
+	go func() {
+		h.syncConnectionCount(client.UserID)
+		if announce {
+			h.updatePresence(client.UserID, true)
+		}
+	}()
 }
 
 // unregisterClient removes a client from the hub
@@ -151,13 +184,22 @@ func (h *WSHub) unregisterClient(client *WSClient) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
+	// A client can be unregistered more than once (e.g. a forced disconnect
+	// followed by its read pump exiting); only tear it down the first time
+	if _, registered := h.clients[client.UserID][client.ID]; !registered {
+		return
+	}
+
 	// Remove from clients map
 	if clients, exists := h.clients[client.UserID]; exists {
 		delete(clients, client.ID)
 		if len(clients) == 0 {
 			delete(h.clients, client.UserID)
-			// User is now offline
-			h.broadcastUserStatus(client.UserID, false)
+			// User is now offline here; the count in Redis is updated once
+			// the debounce has passed
+			h.scheduleOffline(client.UserID)
+		} else {
+			go h.syncConnectionCount(client.UserID)
 		}
 	}
 
@@ -184,9 +226,9 @@ func (h *WSHub) unregisterClient(client *WSClient) {
 func (h *WSHub) broadcastMessage(message WSMessage) {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
-	
+
 	switch message.Type {
-	case WSMessageReceived, WSMessageReaction:  // Add WSMessageReaction here
+	case WSMessageReceived, WSMessageReaction: // Add WSMessageReaction here
 		// Send to all clients in the chat
 		if chatUsers, exists := h.chatRooms[message.ChatID]; exists {
 			for userID, userClients := range chatUsers {
@@ -195,7 +237,7 @@ func (h *WSHub) broadcastMessage(message WSMessage) {
 				if message.Type == WSMessageReceived && userID == message.UserID {
 					continue // Don't send message back to sender
 				}
-				
+
 				for _, client := range userClients {
 					select {
 					case client.Send <- message:
@@ -206,16 +248,16 @@ func (h *WSHub) broadcastMessage(message WSMessage) {
 				}
 			}
 		}
-		
-	case WSUserOnline, WSUserOffline:
-		// Send to all contacts of the user
+
+	case WSUserOnline, WSUserOffline, WSProfileUpdated:
+		// Send to the recipients resolved for the event
 		h.broadcastToUserContacts(message.UserID, message)
-		
+
 	case WSTypingStart, WSTypingStop:
 		// Send to other users in the chat
 		if chatUsers, exists := h.chatRooms[message.ChatID]; exists {
 			for userID, userClients := range chatUsers {
-				if userID != message.UserID { // Don't send to the typing user
+				if userID != message.UserID && !message.excludedUsers[userID] { // Don't send to the typing user or blocked users
 					for _, client := range userClients {
 						select {
 						case client.Send <- message:
@@ -260,38 +302,57 @@ func (h *WSHub) SendTypingIndicator(chatID uuid.UUID, userID uuid.UUID, username
 			"username":  username,
 			"is_typing": isTyping,
 		},
-		Timestamp: time.Now(),
+		Timestamp:     time.Now(),
+		excludedUsers: h.blockedUserSet(userID),
 	}
 
 	h.broadcast <- wsMessage
 }
 
-// broadcastUserStatus sends user online/offline status to contacts
-func (h *WSHub) broadcastUserStatus(userID uuid.UUID, isOnline bool) {
-	msgType := WSUserOnline
-	if !isOnline {
-		msgType = WSUserOffline
-	}
+// JoinChatRoom adds a client to a chat room
+func (h *WSHub) JoinChatRoom(client *WSClient, chatID uuid.UUID) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 
-	wsMessage := WSMessage{
-		Type:   msgType,
-		UserID: userID,
-		Content: map[string]interface{}{
-			"user_id":   userID,
-			"is_online": isOnline,
-			"last_seen": time.Now(),
-		},
-		Timestamp: time.Now(),
-	}
+	h.joinChatRoom(client, chatID)
+}
 
-	h.broadcast <- wsMessage
+// LeaveChatRoom removes a client from a chat room
+func (h *WSHub) LeaveChatRoom(client *WSClient, chatID uuid.UUID) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.leaveChatRoom(client, chatID)
 }
 
-// JoinChatRoom adds a client to a chat room
-func (h *WSHub) JoinChatRoom(client *WSClient, chatID uuid.UUID) {
+// SubscribeUsersToChat adds every live connection of the given users to a
+// chat room, e.g. after they were added to a group
+func (h *WSHub) SubscribeUsersToChat(chatID uuid.UUID, userIDs ...uuid.UUID) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, userID := range userIDs {
+		for _, client := range h.clients[userID] {
+			h.joinChatRoom(client, chatID)
+		}
+	}
+}
+
+// UnsubscribeUsersFromChat drops every live connection of the given users
+// from a chat room, e.g. after they were removed from a group
+func (h *WSHub) UnsubscribeUsersFromChat(chatID uuid.UUID, userIDs ...uuid.UUID) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
+	for _, userID := range userIDs {
+		for _, client := range h.clients[userID] {
+			h.leaveChatRoom(client, chatID)
+		}
+	}
+}
+
+// joinChatRoom adds a client to a chat room. Callers hold the hub lock.
+func (h *WSHub) joinChatRoom(client *WSClient, chatID uuid.UUID) {
 	// Initialize chat room if it doesn't exist
 	if h.chatRooms[chatID] == nil {
 		h.chatRooms[chatID] = make(map[uuid.UUID]map[string]*WSClient)
@@ -313,11 +374,8 @@ func (h *WSHub) JoinChatRoom(client *WSClient, chatID uuid.UUID) {
 	log.Printf("👥 Client %s joined chat %s", client.ID, chatID)
 }
 
-// LeaveChatRoom removes a client from a chat room
-func (h *WSHub) LeaveChatRoom(client *WSClient, chatID uuid.UUID) {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
-
+// leaveChatRoom removes a client from a chat room. Callers hold the hub lock.
+func (h *WSHub) leaveChatRoom(client *WSClient, chatID uuid.UUID) {
 	if chatUsers, exists := h.chatRooms[chatID]; exists {
 		if userClients, exists := chatUsers[client.UserID]; exists {
 			delete(userClients, client.ID)
@@ -325,6 +383,9 @@ func (h *WSHub) LeaveChatRoom(client *WSClient, chatID uuid.UUID) {
 				delete(chatUsers, client.UserID)
 			}
 		}
+		if len(chatUsers) == 0 {
+			delete(h.chatRooms, chatID)
+		}
 	}
 
 	// Remove from client's tracking
@@ -335,14 +396,91 @@ func (h *WSHub) LeaveChatRoom(client *WSClient, chatID uuid.UUID) {
 	log.Printf("👋 Client %s left chat %s", client.ID, chatID)
 }
 
-// broadcastToUserContacts sends a message to all contacts of a user
+// broadcastToUserContacts sends a presence or profile event to its connected
+// recipients (see presenceAudience). Callers hold the hub read lock.
 func (h *WSHub) broadcastToUserContacts(userID uuid.UUID, message WSMessage) {
-	// TODO: Implement contact-based broadcasting
-	// This would require getting the user's contacts from the database
+	for recipientID := range message.recipients {
+		if recipientID == userID || message.excludedUsers[recipientID] {
+			continue
+		}
+
+		userClients, online := h.clients[recipientID]
+		if !online {
+			continue
+		}
+
+		for _, client := range userClients {
+			select {
+			case client.Send <- message:
+			default:
+				log.Printf("⚠️ Dropping %s event for slow client %s", message.Type, client.ID)
+			}
+		}
+	}
+}
+
+// SendToUsers delivers a message to every connected client of the given users
+func (h *WSHub) SendToUsers(userIDs []uuid.UUID, message WSMessage) {
+	// Hold the read lock while sending so unregisterClient cannot close a
+	// Send channel underneath us
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for _, userID := range userIDs {
+		for _, client := range h.clients[userID] {
+			select {
+			case client.Send <- message:
+			default:
+				log.Printf("⚠️ Dropping %s event for slow client %s", message.Type, client.ID)
+			}
+		}
+	}
+}
+
+// DisconnectSession closes every connection opened with the given login session
+func (h *WSHub) DisconnectSession(sessionID uuid.UUID) {
+	h.mutex.RLock()
+	var targets []*WSClient
+	for _, userClients := range h.clients {
+		for _, client := range userClients {
+			if client.SessionID == sessionID {
+				targets = append(targets, client)
+			}
+		}
+	}
+	h.mutex.RUnlock()
+
+	for _, client := range targets {
+		client.forceClose("session terminated")
+	}
+}
+
+// DisconnectUser closes every connection belonging to the user
+func (h *WSHub) DisconnectUser(userID uuid.UUID) {
+	h.mutex.RLock()
+	var targets []*WSClient
+	for _, client := range h.clients[userID] {
+		targets = append(targets, client)
+	}
+	h.mutex.RUnlock()
+
+	for _, client := range targets {
+		client.forceClose("session terminated")
+	}
 }
 
 // Client methods
 
+// forceClose tells the client why it is being dropped and closes the socket.
+// The read pump then fails and unregisters the client as usual.
+func (c *WSClient) forceClose(reason string) {
+	closeMessage := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	c.Conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+	c.Conn.Close()
+
+	log.Printf("🔒 Client %s disconnected: %s", c.ID, reason)
+}
+
 // readPump handles incoming WebSocket messages
 func (c *WSClient) readPump() {
 	defer func() {
@@ -469,6 +607,27 @@ func generateClientID() string {
 	return fmt.Sprintf("client_%s_%d", uuid.New().String()[:8], time.Now().Unix())
 }
 
+// redisEnvelope is a WSMessage as published to Redis, carrying the
+// recipients that are not part of the client payload
+type redisEnvelope struct {
+	WSMessage
+	Recipients []uuid.UUID `json:"recipients,omitempty"`
+}
+
+// PublishToUsers delivers a message to the given users on every server by
+// publishing it on the user_status channel. If Redis is unavailable only
+// this server's clients receive it.
+func (h *WSHub) PublishToUsers(userIDs []uuid.UUID, message WSMessage) {
+	payload, err := json.Marshal(redisEnvelope{WSMessage: message, Recipients: userIDs})
+	if err == nil {
+		err = h.redis.Publish(context.Background(), "user_status", payload).Err()
+	}
+	if err != nil {
+		log.Printf("❌ Failed to publish %s event: %v", message.Type, err)
+		h.SendToUsers(userIDs, message)
+	}
+}
+
 // RedisSubscriber handles Redis pub/sub for cross-server communication
 func (h *WSHub) RedisSubscriber(ctx context.Context) {
 	pubsub := h.redis.Subscribe(ctx, "chat_messages", "user_status", "typing_indicators")
@@ -479,10 +638,18 @@ func (h *WSHub) RedisSubscriber(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case msg := <-pubsub.Channel():
-			var wsMessage WSMessage
-			if err := json.Unmarshal([]byte(msg.Payload), &wsMessage); err == nil {
-				h.broadcast <- wsMessage
+			var envelope redisEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				continue
+			}
+			wsMessage := envelope.WSMessage
+			if len(envelope.Recipients) > 0 {
+				wsMessage.recipients = make(map[uuid.UUID]bool, len(envelope.Recipients))
+				for _, userID := range envelope.Recipients {
+					wsMessage.recipients[userID] = true
+				}
 			}
+			h.broadcast <- wsMessage
 		}
 	}
 }
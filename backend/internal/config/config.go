@@ -2,9 +2,12 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
 	"github.com/caarlos0/env/v10"
+	"github.com/joho/godotenv"
 )
 
 type Config struct {
@@ -26,11 +29,25 @@ type Config struct {
 	ServerPort string `env:"SERVER_PORT"`
 	ServerHost string `env:"SERVER_HOST"`
 	GinMode    string `env:"GIN_MODE"`
+	// Comma-separated IPs or CIDRs of reverse proxies whose X-Forwarded-For
+	// header is trusted for the client IP. Empty trusts no proxy, so per-IP
+	// rate limits use the connection's remote address.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
 
 	// JWT Configuration
 	JWTSecret        string `env:"JWT_SECRET"`
 	JWTExpiry        string `env:"JWT_EXPIRY"`
 	JWTRefreshExpiry string `env:"JWT_REFRESH_EXPIRY"`
+	JWTSigningAlg    string `env:"JWT_SIGNING_ALG"` // HS256 (default), EdDSA or RS256
+	JWTKeyID         string `env:"JWT_KEY_ID"`      // kid of the active signing key
+	JWTPrivateKey    string `env:"JWT_PRIVATE_KEY"` // PEM private key, alternative to JWT_KEY_DIR
+	JWTKeyDir        string `env:"JWT_KEY_DIR"`     // Directory of <kid>.pem and <kid>.pub.pem files
+	// RFC 3339 time until which kid-less HS256 tokens are still accepted
+	// after switching JWT_SIGNING_ALG to EdDSA or RS256
+	JWTLegacyHS256Until string `env:"JWT_LEGACY_HS256_UNTIL"`
+
+	// Two-Factor Authentication
+	TOTPIssuer string `env:"TOTP_ISSUER"` // Name shown in authenticator apps
 
 	// File Storage (MinIO/S3)
 	MinioEndpoint  string `env:"MINIO_ENDPOINT"`
@@ -40,7 +57,7 @@ type Config struct {
 	MinioUseSSL    string `env:"MINIO_USE_SSL"`
 
 	// SMS Service (for phone verification - add your provider)
-	SMSProvider       string `env:"SMS_PROVIDER"`
+	SMSProvider       string `env:"SMS_PROVIDER"` // "twilio", or "log" for local development
 	TwilioAccountSID  string `env:"TWILIO_ACCOUNT_SID"`
 	TwilioAuthToken   string `env:"TWILIO_AUTH_TOKEN"`
 	TwilioPhoneNumber string `env:"TWILIO_PHONE_NUMBER"`
@@ -48,6 +65,12 @@ type Config struct {
 	// Encryption
 	EncryptionKey string `env:"ENCRYPTION_KEY"`
 
+	// Usernames
+	ReservedUsernames string `env:"RESERVED_USERNAMES"` // Comma-separated words usernames may not start with
+
+	// Account Self-Destruct
+	AccountTTLDryRun string `env:"ACCOUNT_TTL_DRY_RUN"` // Inactive accounts are only reported unless set to "false"
+
 	// Development Settings
 	LogLevel                   string `env:"LOG_LEVEL"`
 	EnableCORS                 string `env:"ENABLE_CORS"`
@@ -65,6 +88,42 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("error parsing environment variables: %w", err)
 	}
 	return config, nil
+}
+
+// ReloadConfig re-reads the .env file, letting its values replace those
+// loaded at startup, and parses the environment again
+func ReloadConfig() (*Config, error) {
+	config := &Config{}
+	if err := godotenv.Overload(); err != nil {
+		return nil, fmt.Errorf("error loading .env file: %w", err)
+	}
+
+	if err := env.Parse(config); err != nil {
+		return nil, fmt.Errorf("error parsing environment variables: %w", err)
+	}
+	return config, nil
+}
 
+// ParseDuration parses durations such as "15m", "24h" or "30d", returning
+// fallback when the value is empty or malformed. Day suffixes are accepted
+// because refresh lifetimes are usually expressed in days.
+func ParseDuration(value string, fallback time.Duration) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fallback
+	}
 
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return fallback
+		}
+		return time.Duration(n) * 24 * time.Hour
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
 }
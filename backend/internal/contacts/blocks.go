@@ -0,0 +1,151 @@
+// internal/contacts/blocks.go
+package contacts
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCannotBlockSelf = errors.New("you cannot block yourself")
+	ErrNotBlocked      = errors.New("user is not blocked")
+)
+
+// BlockUser blocks a user, whether or not they are a saved contact. The
+// blocked user is not told; the pair simply stops being mutual.
+func (s *ContactService) BlockUser(userID, targetID uuid.UUID) error {
+	if userID == targetID {
+		return ErrCannotBlockSelf
+	}
+
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND status = 'active')`, targetID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockContactPairs(tx, userID, targetID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO user_contacts (user_id, contact_user_id, is_contact, is_blocked, blocked_at)
+		VALUES ($1, $2, false, true, NOW())
+		ON CONFLICT (user_id, contact_user_id) DO UPDATE
+		SET is_blocked = true, blocked_at = COALESCE(user_contacts.blocked_at, NOW())`
+	if _, err := tx.Exec(query, userID, targetID); err != nil {
+		return err
+	}
+
+	if _, err := setMutual(tx, userID, targetID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.wsHub.InvalidatePresenceAudience(userID, targetID)
+	s.notifyContactsChanged(userID, map[string]interface{}{
+		"action":  "blocked",
+		"user_id": targetID,
+	})
+
+	return nil
+}
+
+// UnblockUser lifts a block. Rows that only existed for the block are removed.
+func (s *ContactService) UnblockUser(userID, targetID uuid.UUID) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockContactPairs(tx, userID, targetID); err != nil {
+		return err
+	}
+
+	var isContact bool
+	query := `
+		SELECT is_contact FROM user_contacts
+		WHERE user_id = $1 AND contact_user_id = $2 AND is_blocked = true
+		FOR UPDATE`
+	if err := tx.QueryRow(query, userID, targetID).Scan(&isContact); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotBlocked
+		}
+		return err
+	}
+
+	if isContact {
+		query = `UPDATE user_contacts SET is_blocked = false, blocked_at = NULL WHERE user_id = $1 AND contact_user_id = $2`
+	} else {
+		query = `DELETE FROM user_contacts WHERE user_id = $1 AND contact_user_id = $2`
+	}
+	if _, err := tx.Exec(query, userID, targetID); err != nil {
+		return err
+	}
+
+	if isContact {
+		if _, err := setMutual(tx, userID, targetID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.wsHub.InvalidatePresenceAudience(userID, targetID)
+	s.notifyContactsChanged(userID, map[string]interface{}{
+		"action":  "unblocked",
+		"user_id": targetID,
+	})
+
+	return nil
+}
+
+// ListBlockedUsers returns the users the user has blocked, most recent first
+func (s *ContactService) ListBlockedUsers(userID uuid.UUID) ([]BlockedUser, error) {
+	query := `
+		SELECT u.id, COALESCE(u.username, ''), COALESCE(u.first_name, ''), COALESCE(u.last_name, ''),
+		       ` + auth.AvatarURLExpr("u") + `, uc.blocked_at
+		FROM user_contacts uc
+		JOIN users u ON u.id = uc.contact_user_id
+		WHERE uc.user_id = $1 AND uc.is_blocked = true
+		ORDER BY uc.blocked_at DESC`
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocked := []BlockedUser{}
+	for rows.Next() {
+		var user BlockedUser
+		var avatarURL sql.NullString
+		var blockedAt sql.NullTime
+		err := rows.Scan(&user.UserID, &user.Username, &user.FirstName, &user.LastName, &avatarURL, &blockedAt)
+		if err != nil {
+			return nil, err
+		}
+		user.AvatarURL = avatarURL.String
+		user.BlockedAt = blockedAt.Time
+		blocked = append(blocked, user)
+	}
+
+	return blocked, rows.Err()
+}
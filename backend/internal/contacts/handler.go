@@ -0,0 +1,229 @@
+// internal/contacts/handler.go
+package contacts
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ContactHandler struct {
+	contactService *ContactService
+}
+
+func NewContactHandler(contactService *ContactService) *ContactHandler {
+	return &ContactHandler{
+		contactService: contactService,
+	}
+}
+
+// ListContacts returns the current user's contacts
+// GET /api/v1/contacts
+func (h *ContactHandler) ListContacts(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	contacts, err := h.contactService.ListContacts(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get contacts",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contacts retrieved successfully",
+		"data":    contacts,
+	})
+}
+
+// AddContact adds a user to the current user's contacts
+// POST /api/v1/contacts
+func (h *ContactHandler) AddContact(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req AddContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	contact, err := h.contactService.AddContact(user.Id, &req)
+	if err != nil {
+		respondContactError(c, err, "Failed to add contact")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contact saved successfully",
+		"data":    contact,
+	})
+}
+
+// ImportContacts matches the device phone book against registered users
+// POST /api/v1/contacts/import
+func (h *ContactHandler) ImportContacts(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req ImportContactsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.contactService.ImportContacts(user.Id, &req)
+	if err != nil {
+		respondContactError(c, err, "Failed to import contacts")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contacts imported successfully",
+		"data":    result,
+	})
+}
+
+// RemoveContact removes a user from the current user's contacts
+// DELETE /api/v1/contacts/:user_id
+func (h *ContactHandler) RemoveContact(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	contactID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	if err := h.contactService.RemoveContact(user.Id, contactID); err != nil {
+		respondContactError(c, err, "Failed to remove contact")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contact removed successfully",
+	})
+}
+
+// ListBlockedUsers returns the users the current user has blocked
+// GET /api/v1/contacts/blocked
+func (h *ContactHandler) ListBlockedUsers(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	blocked, err := h.contactService.ListBlockedUsers(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get blocked users",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Blocked users retrieved successfully",
+		"data":    blocked,
+	})
+}
+
+// BlockUser blocks a user for the current user
+// POST /api/v1/contacts/blocked
+func (h *ContactHandler) BlockUser(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req BlockUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.contactService.BlockUser(user.Id, req.UserID); err != nil {
+		respondContactError(c, err, "Failed to block user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User blocked successfully",
+	})
+}
+
+// UnblockUser lifts a block
+// DELETE /api/v1/contacts/blocked/:user_id
+func (h *ContactHandler) UnblockUser(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	targetID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	if err := h.contactService.UnblockUser(user.Id, targetID); err != nil {
+		respondContactError(c, err, "Failed to unblock user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User unblocked successfully",
+	})
+}
+
+// respondContactError maps contact service errors to HTTP responses
+func respondContactError(c *gin.Context, err error, fallback string) {
+	var rateLimitErr *auth.RateLimitError
+	switch {
+	case errors.As(err, &rateLimitErr):
+		retryAfter := int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many requests, please try again later",
+			"code":        "RATE_LIMITED",
+			"retry_after": retryAfter,
+		})
+	case errors.Is(err, ErrImportTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrMissingContactUser), errors.Is(err, ErrCannotAddSelf),
+		errors.Is(err, ErrContactNameTooLong), errors.Is(err, ErrContactNameRequired),
+		errors.Is(err, ErrCannotBlockSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrContactNotFound), errors.Is(err, ErrNotBlocked):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,355 @@
+// internal/contacts/import.go
+package contacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Phone book imports reveal which numbers have accounts, so they are capped
+// both per request and by how many previously unseen numbers (matched or
+// not) a user may check per day. The daily allowance is shared with search
+// by phone number.
+const (
+	maxImportBatch         = 5000
+	importRequestLimit     = 30
+	importRequestWindow    = time.Hour
+	importRequestKeyPrefix = "contacts:import_requests:" // + user ID
+)
+
+// Mirrors the valid_phone constraint on users
+var phoneNumberPattern = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+
+var ErrImportTooLarge = fmt.Errorf("at most %d contacts can be imported at once", maxImportBatch)
+
+// ImportContacts matches phone book entries against discoverable accounts
+// and saves the matches as contacts under their phone book names. Numbers
+// the user has imported before, matched or not, do not count against the
+// daily quota, so clients can re-sync the whole phone book. When the quota
+// runs out part way, the rest of the new numbers are left for a later sync.
+func (s *ContactService) ImportContacts(userID uuid.UUID, req *ImportContactsRequest) (*ImportContactsResponse, error) {
+	if len(req.Contacts) > maxImportBatch {
+		return nil, ErrImportTooLarge
+	}
+
+	entries, invalid := normalizePhoneBook(req.Contacts)
+	response := &ImportContactsResponse{
+		Imported:     []Contact{},
+		InvalidCount: invalid,
+	}
+	if len(entries) == 0 {
+		return response, nil
+	}
+
+	ctx := context.Background()
+	result, err := s.limiter.Allow(ctx, importRequestKeyPrefix+userID.String(), importRequestLimit, importRequestWindow)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Allowed {
+		return nil, &auth.RateLimitError{Reason: "too many contact imports", RetryAfter: result.RetryAfter}
+	}
+
+	phoneNumbers := make([]string, 0, len(entries))
+	for phoneNumber := range entries {
+		phoneNumbers = append(phoneNumbers, phoneNumber)
+	}
+
+	seen, err := s.getSeenPhoneNumbers(userID, phoneNumbers)
+	if err != nil {
+		return nil, err
+	}
+	checked := make([]string, 0, len(phoneNumbers))
+	newNumbers := []string{}
+	for _, phoneNumber := range phoneNumbers {
+		if seen[phoneNumber] {
+			checked = append(checked, phoneNumber)
+		} else {
+			newNumbers = append(newNumbers, phoneNumber)
+		}
+	}
+	// A stable order lets repeated syncs work through a large phone book
+	sort.Strings(newNumbers)
+
+	result, err = s.limiter.AllowUpTo(ctx, auth.PhoneLookupKeyPrefix+userID.String(), len(newNumbers), auth.PhoneLookupLimit, auth.PhoneLookupWindow)
+	if err != nil {
+		return nil, err
+	}
+	if result.Granted == 0 && len(checked) == 0 {
+		return nil, &auth.RateLimitError{Reason: "too many new phone numbers imported", RetryAfter: result.RetryAfter}
+	}
+	accepted := newNumbers[:result.Granted]
+	if !result.Allowed {
+		response.DeferredCount = len(newNumbers) - len(accepted)
+		response.RetryAfter = int(math.Ceil(result.RetryAfter.Seconds()))
+	}
+
+	if err := s.markPhoneNumbersSeen(userID, accepted); err != nil {
+		return nil, err
+	}
+	checked = append(checked, accepted...)
+
+	matches, err := s.findDiscoverableUsers(userID, checked)
+	if err != nil {
+		return nil, err
+	}
+	response.UnmatchedCount = len(checked) - len(matches)
+	if len(matches) == 0 {
+		return response, nil
+	}
+
+	contactIDs := make([]uuid.UUID, 0, len(matches))
+	for contactID := range matches {
+		contactIDs = append(contactIDs, contactID)
+	}
+
+	newlyMutual, err := s.saveImportedContacts(userID, matches, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	s.wsHub.InvalidatePresenceAudience(append(contactIDs, userID)...)
+
+	imported, err := s.getContacts(userID, contactIDs)
+	if err != nil {
+		return nil, err
+	}
+	response.Imported = imported
+	response.ImportedCount = len(imported)
+
+	s.notifyContactsChanged(userID, map[string]interface{}{
+		"action":   "imported",
+		"contacts": imported,
+	})
+	for _, contactID := range newlyMutual {
+		s.notifyMutualChanged(contactID, userID, true)
+	}
+
+	return response, nil
+}
+
+// normalizePhoneBook validates entries and merges duplicates by number. It
+// returns the entries keyed by phone number and how many were skipped.
+func normalizePhoneBook(book []PhoneBookEntry) (map[string]PhoneBookEntry, int) {
+	entries := make(map[string]PhoneBookEntry, len(book))
+	invalid := 0
+
+	for _, entry := range book {
+		entry.PhoneNumber = strings.Join(strings.Fields(entry.PhoneNumber), "")
+		entry.FirstName = strings.TrimSpace(entry.FirstName)
+		entry.LastName = strings.TrimSpace(entry.LastName)
+
+		if !phoneNumberPattern.MatchString(entry.PhoneNumber) ||
+			utf8.RuneCountInString(entry.FirstName) > maxContactNameLength ||
+			utf8.RuneCountInString(entry.LastName) > maxContactNameLength {
+			invalid++
+			continue
+		}
+		if entry.FirstName == "" {
+			entry.LastName = ""
+		}
+
+		entries[entry.PhoneNumber] = entry
+	}
+
+	return entries, invalid
+}
+
+// getSeenPhoneNumbers returns which of the numbers the user has imported before
+func (s *ContactService) getSeenPhoneNumbers(userID uuid.UUID, phoneNumbers []string) (map[string]bool, error) {
+	byHash := make(map[string]string, len(phoneNumbers))
+	hashes := make([]string, 0, len(phoneNumbers))
+	for _, phoneNumber := range phoneNumbers {
+		hash := hashPhoneNumber(phoneNumber)
+		byHash[hash] = phoneNumber
+		hashes = append(hashes, hash)
+	}
+
+	query := `
+		SELECT phone_hash FROM contact_import_numbers
+		WHERE user_id = $1 AND phone_hash = ANY($2)`
+
+	rows, err := s.db.Query(query, userID, pq.Array(hashes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		seen[byHash[hash]] = true
+	}
+
+	return seen, rows.Err()
+}
+
+// markPhoneNumbersSeen remembers numbers the user has imported
+func (s *ContactService) markPhoneNumbersSeen(userID uuid.UUID, phoneNumbers []string) error {
+	if len(phoneNumbers) == 0 {
+		return nil
+	}
+
+	hashes := make([]string, 0, len(phoneNumbers))
+	for _, phoneNumber := range phoneNumbers {
+		hashes = append(hashes, hashPhoneNumber(phoneNumber))
+	}
+
+	query := `
+		INSERT INTO contact_import_numbers (user_id, phone_hash)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING`
+	_, err := s.db.Exec(query, userID, pq.Array(hashes))
+	return err
+}
+
+// hashPhoneNumber returns the SHA-256 hex digest stored for imported numbers
+func hashPhoneNumber(phoneNumber string) string {
+	sum := sha256.Sum256([]byte(phoneNumber))
+	return hex.EncodeToString(sum[:])
+}
+
+// findDiscoverableUsers returns active accounts that allow being found by
+// phone number and have not blocked the importer, keyed by user ID
+func (s *ContactService) findDiscoverableUsers(userID uuid.UUID, phoneNumbers []string) (map[uuid.UUID]string, error) {
+	query := `
+		SELECT u.id, u.phone_number FROM users u
+		WHERE u.phone_number = ANY($1) AND u.id != $2
+		  AND u.allow_phone_discovery = true AND u.status = 'active'
+		  AND NOT EXISTS(
+		      SELECT 1 FROM user_contacts b
+		      WHERE b.user_id = u.id AND b.contact_user_id = $2 AND b.is_blocked = true)`
+
+	rows, err := s.db.Query(query, pq.Array(phoneNumbers), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var id uuid.UUID
+		var phoneNumber string
+		if err := rows.Scan(&id, &phoneNumber); err != nil {
+			return nil, err
+		}
+		matches[id] = phoneNumber
+	}
+
+	return matches, rows.Err()
+}
+
+// saveImportedContacts upserts the matched contacts and marks new mutual
+// pairs. It returns the users whose side of the pair became mutual.
+func (s *ContactService) saveImportedContacts(userID uuid.UUID, matches map[uuid.UUID]string, entries map[string]PhoneBookEntry) ([]uuid.UUID, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	contactIDs := make([]uuid.UUID, 0, len(matches))
+	for contactID := range matches {
+		contactIDs = append(contactIDs, contactID)
+	}
+	if err := lockContactPairs(tx, userID, contactIDs...); err != nil {
+		return nil, err
+	}
+
+	// Phone book names replace saved names only when the entry has one
+	query := `
+		INSERT INTO user_contacts (user_id, contact_user_id, first_name, last_name, phone_number)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5)
+		ON CONFLICT (user_id, contact_user_id) DO UPDATE
+		SET is_contact = true,
+		    phone_number = EXCLUDED.phone_number,
+		    first_name = COALESCE(EXCLUDED.first_name, user_contacts.first_name),
+		    last_name = CASE WHEN EXCLUDED.first_name IS NULL THEN user_contacts.last_name ELSE EXCLUDED.last_name END`
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for contactID, phoneNumber := range matches {
+		entry := entries[phoneNumber]
+		if _, err := stmt.Exec(userID, contactID, entry.FirstName, entry.LastName, phoneNumber); err != nil {
+			return nil, err
+		}
+	}
+
+	query = `
+		UPDATE user_contacts uc SET is_mutual = true
+		WHERE ((uc.user_id = $1 AND uc.contact_user_id = ANY($2))
+		    OR (uc.contact_user_id = $1 AND uc.user_id = ANY($2)))
+		  AND uc.is_mutual = false AND uc.is_contact = true AND uc.is_blocked = false
+		  AND EXISTS(SELECT 1 FROM user_contacts r
+		             WHERE r.user_id = uc.contact_user_id AND r.contact_user_id = uc.user_id
+		               AND r.is_contact = true AND r.is_blocked = false)
+		RETURNING uc.user_id`
+
+	rows, err := tx.Query(query, userID, pq.Array(contactIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var newlyMutual []uuid.UUID
+	for rows.Next() {
+		var ownerID uuid.UUID
+		if err := rows.Scan(&ownerID); err != nil {
+			return nil, err
+		}
+		if ownerID != userID {
+			newlyMutual = append(newlyMutual, ownerID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return newlyMutual, nil
+}
+
+// getContacts loads the given contacts of a user
+func (s *ContactService) getContacts(userID uuid.UUID, contactIDs []uuid.UUID) ([]Contact, error) {
+	query := contactSelect + `
+		WHERE uc.user_id = $1 AND uc.contact_user_id = ANY($2)
+		ORDER BY LOWER(CASE WHEN COALESCE(uc.first_name, '') != '' THEN uc.first_name ELSE u.first_name END), u.username`
+
+	rows, err := s.db.Query(query, userID, pq.Array(contactIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := []Contact{}
+	for rows.Next() {
+		contact, err := scanContact(rows)
+		if err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, *contact)
+	}
+
+	return contacts, rows.Err()
+}
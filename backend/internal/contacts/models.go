@@ -0,0 +1,70 @@
+// internal/contacts/models.go
+package contacts
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Contact is a user saved in the current user's contact list
+type Contact struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name"` // Name the owner saved, falling back to the profile name
+	LastName  string    `json:"last_name,omitempty"`
+	AvatarURL string    `json:"avatar_url,omitempty"`
+	IsMutual  bool      `json:"is_mutual"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// AddContactRequest adds a user by ID or username, optionally under a custom name
+type AddContactRequest struct {
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	Username  string     `json:"username,omitempty"`
+	FirstName string     `json:"first_name,omitempty"`
+	LastName  string     `json:"last_name,omitempty"`
+}
+
+// ContactListResponse for the contact list
+type ContactListResponse struct {
+	Contacts   []Contact `json:"contacts"`
+	TotalCount int       `json:"total_count"`
+}
+
+// PhoneBookEntry is one contact from the device phone book
+type PhoneBookEntry struct {
+	PhoneNumber string `json:"phone_number"`
+	FirstName   string `json:"first_name,omitempty"`
+	LastName    string `json:"last_name,omitempty"`
+}
+
+// ImportContactsRequest uploads (part of) the device phone book
+type ImportContactsRequest struct {
+	Contacts []PhoneBookEntry `json:"contacts" binding:"required"`
+}
+
+// ImportContactsResponse lists the phone book entries that are on the app
+type ImportContactsResponse struct {
+	Imported       []Contact `json:"imported"`
+	ImportedCount  int       `json:"imported_count"`
+	UnmatchedCount int       `json:"unmatched_count"`       // Valid numbers with no discoverable account
+	InvalidCount   int       `json:"invalid_count"`         // Entries skipped for a malformed number or name
+	DeferredCount  int       `json:"deferred_count"`        // New numbers not checked because the daily quota ran out
+	RetryAfter     int       `json:"retry_after,omitempty"` // Seconds until deferred numbers can be checked
+}
+
+// BlockedUser is a user the current user has blocked
+type BlockedUser struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name,omitempty"`
+	AvatarURL string    `json:"avatar_url,omitempty"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// BlockUserRequest blocks a user by ID
+type BlockUserRequest struct {
+	UserID uuid.UUID `json:"user_id" binding:"required"`
+}
@@ -0,0 +1,322 @@
+// internal/contacts/service.go
+package contacts
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/atharva-navani16/chat-app.git/internal/chat"
+	"github.com/atharva-navani16/chat-app.git/internal/shared/ratelimit"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+)
+
+const maxContactNameLength = 100 // matches VARCHAR(100)
+
+var (
+	ErrContactNotFound     = errors.New("contact not found")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrCannotAddSelf       = errors.New("you cannot add yourself as a contact")
+	ErrMissingContactUser  = errors.New("user_id or username is required")
+	ErrContactNameTooLong  = errors.New("contact names must be at most 100 characters")
+	ErrContactNameRequired = errors.New("first_name is required when last_name is set")
+)
+
+type ContactService struct {
+	db      *sql.DB
+	wsHub   *chat.WSHub
+	limiter *ratelimit.Limiter
+}
+
+func NewContactService(db *sql.DB, rdb *redis.Client, wsHub *chat.WSHub) *ContactService {
+	return &ContactService{
+		db:      db,
+		wsHub:   wsHub,
+		limiter: ratelimit.NewLimiter(rdb),
+	}
+}
+
+// ListContacts returns the user's contacts ordered by display name
+func (s *ContactService) ListContacts(userID uuid.UUID) (*ContactListResponse, error) {
+	query := contactSelect + `
+		WHERE uc.user_id = $1 AND uc.is_contact = true AND uc.is_blocked = false AND u.status = 'active'
+		ORDER BY LOWER(CASE WHEN COALESCE(uc.first_name, '') != '' THEN uc.first_name ELSE u.first_name END), u.username`
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := []Contact{}
+	for rows.Next() {
+		contact, err := scanContact(rows)
+		if err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, *contact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &ContactListResponse{
+		Contacts:   contacts,
+		TotalCount: len(contacts),
+	}, nil
+}
+
+// AddContact saves a user to the contact list, or renames an existing
+// contact, and marks the pair mutual if the other side has them saved too
+func (s *ContactService) AddContact(userID uuid.UUID, req *AddContactRequest) (*Contact, error) {
+	firstName := strings.TrimSpace(req.FirstName)
+	lastName := strings.TrimSpace(req.LastName)
+	if utf8.RuneCountInString(firstName) > maxContactNameLength || utf8.RuneCountInString(lastName) > maxContactNameLength {
+		return nil, ErrContactNameTooLong
+	}
+	if firstName == "" && lastName != "" {
+		return nil, ErrContactNameRequired
+	}
+
+	contactID, err := s.resolveUser(req)
+	if err != nil {
+		return nil, err
+	}
+	if contactID == userID {
+		return nil, ErrCannotAddSelf
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockContactPairs(tx, userID, contactID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO user_contacts (user_id, contact_user_id, first_name, last_name)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		ON CONFLICT (user_id, contact_user_id) DO UPDATE
+		SET first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name, is_contact = true
+		RETURNING is_mutual`
+	var wasMutual bool
+	if err := tx.QueryRow(query, userID, contactID, firstName, lastName).Scan(&wasMutual); err != nil {
+		return nil, err
+	}
+
+	isMutual, err := setMutual(tx, userID, contactID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.wsHub.InvalidatePresenceAudience(userID, contactID)
+
+	contact, err := s.getContact(userID, contactID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyContactsChanged(userID, map[string]interface{}{
+		"action":  "added",
+		"contact": contact,
+	})
+	if isMutual != wasMutual {
+		s.notifyMutualChanged(contactID, userID, isMutual)
+	}
+
+	return contact, nil
+}
+
+// RemoveContact deletes a contact and clears the mutual flag on the other
+// side. A blocked contact keeps its row so the block survives.
+func (s *ContactService) RemoveContact(userID, contactID uuid.UUID) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockContactPairs(tx, userID, contactID); err != nil {
+		return err
+	}
+
+	var wasMutual bool
+	query := `
+		DELETE FROM user_contacts
+		WHERE user_id = $1 AND contact_user_id = $2 AND is_contact = true AND is_blocked = false
+		RETURNING is_mutual`
+	err = tx.QueryRow(query, userID, contactID).Scan(&wasMutual)
+	if err == sql.ErrNoRows {
+		query = `
+			UPDATE user_contacts
+			SET is_contact = false, is_mutual = false, first_name = NULL, last_name = NULL, phone_number = NULL
+			WHERE user_id = $1 AND contact_user_id = $2 AND is_contact = true
+			RETURNING false`
+		err = tx.QueryRow(query, userID, contactID).Scan(&wasMutual)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrContactNotFound
+		}
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE user_contacts SET is_mutual = false
+		WHERE user_id = $1 AND contact_user_id = $2`, contactID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.wsHub.InvalidatePresenceAudience(userID, contactID)
+	s.notifyContactsChanged(userID, map[string]interface{}{
+		"action":  "removed",
+		"user_id": contactID,
+	})
+	if wasMutual {
+		s.notifyMutualChanged(contactID, userID, false)
+	}
+
+	return nil
+}
+
+// lockContactPairs serializes changes to the contact rows between userID and
+// each of contactIDs. The users rows are locked in id order because either
+// side's contact row may not exist yet; without the lock two users adding
+// each other at once would both see the other's row missing and leave the
+// pair non-mutual.
+func lockContactPairs(tx *sql.Tx, userID uuid.UUID, contactIDs ...uuid.UUID) error {
+	ids := append([]uuid.UUID{userID}, contactIDs...)
+	_, err := tx.Exec(`SELECT id FROM users WHERE id = ANY($1) ORDER BY id FOR NO KEY UPDATE`, pq.Array(ids))
+	return err
+}
+
+// setMutual recomputes is_mutual on both rows of a pair and returns it.
+// Callers hold the pair lock from lockContactPairs.
+func setMutual(tx *sql.Tx, userID, contactID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE user_contacts uc SET is_mutual = (
+			SELECT COUNT(*) = 2 FROM user_contacts
+			WHERE ((user_id = $1 AND contact_user_id = $2) OR (user_id = $2 AND contact_user_id = $1))
+			  AND is_contact = true AND is_blocked = false
+		)
+		WHERE (uc.user_id = $1 AND uc.contact_user_id = $2) OR (uc.user_id = $2 AND uc.contact_user_id = $1)
+		RETURNING uc.is_mutual`
+
+	var isMutual bool
+	err := tx.QueryRow(query, userID, contactID).Scan(&isMutual)
+	return isMutual, err
+}
+
+// resolveUser finds the active user a request refers to
+func (s *ContactService) resolveUser(req *AddContactRequest) (uuid.UUID, error) {
+	var query string
+	var param interface{}
+
+	switch {
+	case req.UserID != nil:
+		query = `SELECT id FROM users WHERE id = $1 AND status = 'active'`
+		param = *req.UserID
+	case req.Username != "":
+		query = `SELECT id FROM users WHERE LOWER(username) = LOWER($1) AND status = 'active'`
+		param = strings.TrimPrefix(req.Username, "@")
+	default:
+		return uuid.Nil, ErrMissingContactUser
+	}
+
+	var id uuid.UUID
+	if err := s.db.QueryRow(query, param).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, ErrUserNotFound
+		}
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+func (s *ContactService) getContact(userID, contactID uuid.UUID) (*Contact, error) {
+	query := contactSelect + `
+		WHERE uc.user_id = $1 AND uc.contact_user_id = $2`
+
+	contact, err := scanContact(s.db.QueryRow(query, userID, contactID))
+	if err == sql.ErrNoRows {
+		return nil, ErrContactNotFound
+	}
+	return contact, err
+}
+
+// contactSelect reads the columns scanContact expects from user_contacts uc
+// joined to the contact's users row u, preferring the name the owner saved
+var contactSelect = `
+		SELECT u.id, u.username,
+		       CASE WHEN COALESCE(uc.first_name, '') != '' THEN uc.first_name ELSE u.first_name END,
+		       CASE WHEN COALESCE(uc.first_name, '') != '' THEN COALESCE(uc.last_name, '') ELSE COALESCE(u.last_name, '') END,
+		       ` + auth.AvatarURLExpr("u") + `, uc.is_mutual, uc.added_at
+		FROM user_contacts uc
+		JOIN users u ON u.id = uc.contact_user_id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanContact(row rowScanner) (*Contact, error) {
+	var contact Contact
+	var avatarURL sql.NullString
+	var isMutual sql.NullBool
+	var addedAt sql.NullTime
+
+	err := row.Scan(
+		&contact.UserID, &contact.Username, &contact.FirstName, &contact.LastName,
+		&avatarURL, &isMutual, &addedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	contact.AvatarURL = avatarURL.String
+	contact.IsMutual = isMutual.Bool
+	contact.AddedAt = addedAt.Time
+
+	return &contact, nil
+}
+
+// notifyContactsChanged keeps the owner's other devices in sync
+func (s *ContactService) notifyContactsChanged(userID uuid.UUID, content map[string]interface{}) {
+	s.wsHub.SendToUsers([]uuid.UUID{userID}, chat.WSMessage{
+		Type:      chat.WSContactsChanged,
+		UserID:    userID,
+		Content:   content,
+		Timestamp: time.Now(),
+	})
+}
+
+// notifyMutualChanged tells a user that someone in their contacts added or
+// removed them in return
+func (s *ContactService) notifyMutualChanged(userID, contactID uuid.UUID, isMutual bool) {
+	s.wsHub.SendToUsers([]uuid.UUID{userID}, chat.WSMessage{
+		Type:   chat.WSContactsChanged,
+		UserID: userID,
+		Content: map[string]interface{}{
+			"action":    "mutual_changed",
+			"user_id":   contactID,
+			"is_mutual": isMutual,
+		},
+		Timestamp: time.Now(),
+	})
+}
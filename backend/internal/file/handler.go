@@ -2,6 +2,7 @@
 package file
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -174,6 +175,8 @@ func (h *FileHandler) DeleteFile(c *gin.Context) {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "access denied" {
 			statusCode = http.StatusForbidden
+		} else if errors.Is(err, ErrFileInUse) {
+			statusCode = http.StatusConflict
 		}
 		c.JSON(statusCode, gin.H{
 			"error":   "Failed to delete file",
@@ -0,0 +1,299 @@
+// internal/file/profile_photo.go
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register decoder
+	"image/jpeg"
+	_ "image/png" // register decoder
+	"io"
+	"mime/multipart"
+
+	"github.com/google/uuid"
+)
+
+// Profile photos are stored as two square JPEG variants, like Telegram's
+// small (160px) and big (640px) chat photos
+const (
+	MaxProfilePhotoSize   = 5 * 1024 * 1024 // 5MB
+	MaxProfilePhotoPixels = 8_000_000       // bounds decode memory; clients downscale larger photos
+	SmallAvatarSize       = 160
+	BigAvatarSize         = 640
+	avatarJPEGQuality     = 90
+)
+
+// Formats the standard library can decode
+var AllowedProfilePhotoTypes = []string{
+	"image/jpeg", "image/jpg", "image/png", "image/gif",
+}
+
+var (
+	ErrProfilePhotoTooLarge = fmt.Errorf("profile photo too large (max %d bytes)", MaxProfilePhotoSize)
+	ErrInvalidProfilePhoto  = errors.New("profile photo must be a JPEG, PNG or GIF image")
+)
+
+// ProfilePhotoFiles are the stored avatar variants of one profile photo
+type ProfilePhotoFiles struct {
+	Small *File
+	Big   *File
+}
+
+// UploadProfilePhoto validates an uploaded image, crops it to a centred
+// square and stores small and big avatar variants
+func (s *FileService) UploadProfilePhoto(fileHeader *multipart.FileHeader, userID uuid.UUID) (*ProfilePhotoFiles, error) {
+	if fileHeader.Size > MaxProfilePhotoSize {
+		return nil, ErrProfilePhotoTooLarge
+	}
+	if !isAllowedProfilePhotoType(fileHeader.Header.Get("Content-Type")) {
+		return nil, ErrInvalidProfilePhoto
+	}
+
+	uploadedFile, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %v", err)
+	}
+	defer uploadedFile.Close()
+
+	// Enforce the limit on what is actually read, not the declared size
+	content, err := io.ReadAll(io.LimitReader(uploadedFile, MaxProfilePhotoSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	if len(content) > MaxProfilePhotoSize {
+		return nil, ErrProfilePhotoTooLarge
+	}
+
+	// Trust the decoded content, not the Content-Type header
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrInvalidProfilePhoto
+	}
+	if config.Width*config.Height > MaxProfilePhotoPixels {
+		return nil, ErrProfilePhotoTooLarge
+	}
+
+	source, format, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrInvalidProfilePhoto
+	}
+	square := cropToSquare(source)
+	if format == "jpeg" {
+		square = applyOrientation(square, jpegOrientation(content))
+	}
+
+	small, err := s.storeAvatarVariant(square, SmallAvatarSize, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	big, err := s.storeAvatarVariant(square, BigAvatarSize, userID)
+	if err != nil {
+		s.DeleteFile(small.ID, userID)
+		return nil, err
+	}
+
+	return &ProfilePhotoFiles{Small: small, Big: big}, nil
+}
+
+// storeAvatarVariant scales the square image and stores it as a JPEG
+func (s *FileService) storeAvatarVariant(square image.Image, size int, userID uuid.UUID) (*File, error) {
+	// Never upscale small uploads
+	if square.Bounds().Dx() < size {
+		size = square.Bounds().Dx()
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeSquare(square, size), &jpeg.Options{Quality: avatarJPEGQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode avatar: %v", err)
+	}
+
+	name := fmt.Sprintf("avatar_%d.jpg", size)
+	return s.storeFile(buf.Bytes(), name, "image/jpeg", "image", userID)
+}
+
+// cropToSquare returns the centred square region of an image, sharing the
+// decoded pixels when the image type allows it
+func cropToSquare(img image.Image) image.Image {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+	region := image.Rect(x0, y0, x0+side, y0+side)
+
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(region)
+	}
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, region.Min, draw.Src)
+	return square
+}
+
+// applyOrientation turns a square image upright according to its EXIF
+// orientation (1-8). The centred crop is symmetric, so it can be applied
+// after cropping.
+func applyOrientation(square image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return square
+	}
+
+	bounds := square.Bounds()
+	last := bounds.Dx() - 1
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y <= last; y++ {
+		for x := 0; x <= last; x++ {
+			sx, sy := x, y
+			switch orientation {
+			case 2: // flip horizontally
+				sx = last - x
+			case 3: // rotate 180°
+				sx, sy = last-x, last-y
+			case 4: // flip vertically
+				sy = last - y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90° clockwise
+				sx, sy = y, last-x
+			case 7: // transverse
+				sx, sy = last-y, last-x
+			case 8: // rotate 90° counter-clockwise
+				sx, sy = last-y, x
+			}
+			dst.Set(x, y, square.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG, returning 1
+// (upright) when there is none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xFF { // fill byte
+			pos++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // metadata ends where image data starts
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+
+	return 1
+}
+
+// exifOrientation finds the orientation tag (0x0112) in the first IFD of
+// a TIFF-structured EXIF block
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) != 0x0112 {
+			continue
+		}
+		if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+			return orientation
+		}
+		return 1
+	}
+
+	return 1
+}
+
+// resizeSquare downscales a square image by averaging the source pixels that
+// fall into each destination pixel (box filter)
+func resizeSquare(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	srcSize := bounds.Dx()
+	if srcSize == size {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		sy0 := y * srcSize / size
+		sy1 := max((y+1)*srcSize/size, sy0+1)
+
+		for x := 0; x < size; x++ {
+			sx0 := x * srcSize / size
+			sx1 := max((x+1)*srcSize/size, sx0+1)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
+
+func isAllowedProfilePhotoType(contentType string) bool {
+	for _, allowed := range AllowedProfilePhotoTypes {
+		if contentType == allowed {
+			return true
+		}
+	}
+	return false
+}
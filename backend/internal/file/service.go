@@ -5,8 +5,10 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"path/filepath"
 	"time"
@@ -72,33 +74,40 @@ func (s *FileService) UploadFile(fileHeader *multipart.FileHeader, userID uuid.U
 	}
 	defer uploadedFile.Close()
 
+	// Read file content
+	fileContent, err := io.ReadAll(uploadedFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	contentType := fileHeader.Header.Get("Content-Type")
+
+	// Determine file type
+	fileType := s.determineFileType(contentType)
+
+	return s.storeFile(fileContent, fileHeader.Filename, contentType, fileType, userID)
+}
+
+// storeFile uploads content to MinIO and records its metadata
+func (s *FileService) storeFile(fileContent []byte, originalName, contentType, fileType string, userID uuid.UUID) (*File, error) {
 	// Generate file ID and storage path
 	fileID := uuid.New()
-	fileExtension := filepath.Ext(fileHeader.Filename)
+	fileExtension := filepath.Ext(originalName)
 	storagePath := fmt.Sprintf("files/%s/%s/%s%s",
 		userID.String(),
 		time.Now().Format("2006/01/02"),
 		fileID.String(),
 		fileExtension)
 
-	// Determine file type
-	fileType := s.determineFileType(fileHeader.Header.Get("Content-Type"))
-
-	// Read file content
-	fileContent, err := io.ReadAll(uploadedFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %v", err)
-	}
-
 	// Upload to MinIO
-	_, err = s.minioClient.PutObject(
+	_, err := s.minioClient.PutObject(
 		context.Background(),
 		s.bucketName,
 		storagePath,
 		bytes.NewReader(fileContent),
-		fileHeader.Size,
+		int64(len(fileContent)),
 		minio.PutObjectOptions{
-			ContentType: fileHeader.Header.Get("Content-Type"),
+			ContentType: contentType,
 		},
 	)
 	if err != nil {
@@ -111,10 +120,10 @@ func (s *FileService) UploadFile(fileHeader *multipart.FileHeader, userID uuid.U
 	// Create file record
 	file := &File{
 		ID:               fileID,
-		OriginalName:     fileHeader.Filename,
+		OriginalName:     originalName,
 		FileType:         fileType,
-		MimeType:         fileHeader.Header.Get("Content-Type"),
-		FileSize:         fileHeader.Size,
+		MimeType:         contentType,
+		FileSize:         int64(len(fileContent)),
 		StoragePath:      storagePath,
 		CDNUrl:           cdnURL,
 		ProcessingStatus: "completed",
@@ -210,6 +219,14 @@ func (s *FileService) GetFileContent(fileID uuid.UUID, userID uuid.UUID) (io.Rea
 	return object, file, nil
 }
 
+// fileNotInUse matches files (aliased f) that nothing but a message may
+// reference. Avatars are removed through their owning profile photo, and
+// chat photos by changing the chat's photo.
+const fileNotInUse = `NOT EXISTS(SELECT 1 FROM profile_photos pp WHERE pp.small_file_id = f.id OR pp.big_file_id = f.id)
+	AND NOT EXISTS(SELECT 1 FROM chats c WHERE c.photo_id = f.id)`
+
+var ErrFileInUse = errors.New("file is in use")
+
 // DeleteFile deletes a file
 func (s *FileService) DeleteFile(fileID uuid.UUID, userID uuid.UUID) error {
 	// Get file metadata
@@ -223,6 +240,17 @@ func (s *FileService) DeleteFile(fileID uuid.UUID, userID uuid.UUID) error {
 		return fmt.Errorf("access denied")
 	}
 
+	// Delete from database first, so a file that is still referenced never
+	// loses its stored object
+	query := `DELETE FROM files f WHERE f.id = $1 AND f.uploaded_by = $2 AND ` + fileNotInUse
+	result, err := s.db.Exec(query, fileID, userID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrFileInUse
+	}
+
 	// Delete from MinIO
 	err = s.minioClient.RemoveObject(
 		context.Background(),
@@ -231,13 +259,9 @@ func (s *FileService) DeleteFile(fileID uuid.UUID, userID uuid.UUID) error {
 		minio.RemoveObjectOptions{},
 	)
 	if err != nil {
-		return fmt.Errorf("failed to delete from storage: %v", err)
+		log.Printf("❌ Failed to delete %s from storage: %v", file.StoragePath, err)
 	}
-
-	// Delete from database
-	query := `DELETE FROM files WHERE id = $1 AND uploaded_by = $2`
-	_, err = s.db.Exec(query, fileID, userID)
-	return err
+	return nil
 }
 
 // Helper functions
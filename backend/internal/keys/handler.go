@@ -0,0 +1,159 @@
+// internal/keys/handler.go
+package keys
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type KeyHandler struct {
+	keyService *KeyService
+}
+
+func NewKeyHandler(keyService *KeyService) *KeyHandler {
+	return &KeyHandler{
+		keyService: keyService,
+	}
+}
+
+// UploadKeys publishes the current user's identity key and signed prekey
+// PUT /api/v1/keys
+func (h *KeyHandler) UploadKeys(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req UploadKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.keyService.UploadKeys(user.Id, &req); err != nil {
+		respondKeyError(c, err, "Failed to upload keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Keys uploaded successfully",
+	})
+}
+
+// UploadOneTimePrekeys adds one-time prekeys for the current user
+// POST /api/v1/keys/one-time
+func (h *KeyHandler) UploadOneTimePrekeys(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req UploadOneTimePrekeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	count, err := h.keyService.UploadOneTimePrekeys(user.Id, req.OneTimePrekeys)
+	if err != nil {
+		respondKeyError(c, err, "Failed to upload prekeys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Prekeys uploaded successfully",
+		"data": PrekeyCountResponse{
+			OneTimePrekeys: count,
+			Replenish:      count < lowPrekeyWarningCount,
+		},
+	})
+}
+
+// GetPrekeyCount returns how many one-time prekeys the current user has left
+// GET /api/v1/keys/count
+func (h *KeyHandler) GetPrekeyCount(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	count, err := h.keyService.CountOneTimePrekeys(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count prekeys",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Prekey count retrieved successfully",
+		"data": PrekeyCountResponse{
+			OneTimePrekeys: count,
+			Replenish:      count < lowPrekeyWarningCount,
+		},
+	})
+}
+
+// GetPrekeyBundle hands out a user's key bundle, consuming one one-time prekey
+// GET /api/v1/keys/:user_id
+func (h *KeyHandler) GetPrekeyBundle(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	targetID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	bundle, err := h.keyService.GetPrekeyBundle(user.Id, targetID)
+	if err != nil {
+		respondKeyError(c, err, "Failed to get key bundle")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Key bundle retrieved successfully",
+		"data":    bundle,
+	})
+}
+
+// respondKeyError maps key service errors to HTTP responses
+func respondKeyError(c *gin.Context, err error, fallback string) {
+	var rateLimitErr *auth.RateLimitError
+	switch {
+	case errors.As(err, &rateLimitErr):
+		retryAfter := int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many requests, please try again later",
+			"code":        "RATE_LIMITED",
+			"retry_after": retryAfter,
+		})
+	case errors.Is(err, ErrInvalidIdentityKey), errors.Is(err, ErrInvalidPrekey),
+		errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrTooManyPrekeys):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrDuplicatePrekeyID), errors.Is(err, ErrIdentityKeyMissing):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrKeyBundleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,49 @@
+package keys
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Keys are raw 32-byte public keys, base64 encoded in JSON
+
+// SignedPrekey is a medium-term X25519 prekey signed by the identity key
+type SignedPrekey struct {
+	KeyID     int    `json:"key_id"`
+	PublicKey []byte `json:"public_key" binding:"required"`
+	Signature []byte `json:"signature" binding:"required"`
+}
+
+// OneTimePrekey is a single-use X25519 prekey
+type OneTimePrekey struct {
+	KeyID     int    `json:"key_id"`
+	PublicKey []byte `json:"public_key" binding:"required"`
+}
+
+// UploadKeysRequest replaces the identity key and signed prekey, optionally
+// adding one-time prekeys
+type UploadKeysRequest struct {
+	IdentityKey    []byte          `json:"identity_key" binding:"required"`
+	SignedPrekey   SignedPrekey    `json:"signed_prekey" binding:"required"`
+	OneTimePrekeys []OneTimePrekey `json:"one_time_prekeys,omitempty"`
+}
+
+type UploadOneTimePrekeysRequest struct {
+	OneTimePrekeys []OneTimePrekey `json:"one_time_prekeys" binding:"required"`
+}
+
+// PrekeyBundle is what an initiator needs to start a session with a user.
+// OneTimePrekey is omitted once the user has run out.
+type PrekeyBundle struct {
+	UserID        uuid.UUID      `json:"user_id"`
+	IdentityKey   []byte         `json:"identity_key"`
+	SignedPrekey  SignedPrekey   `json:"signed_prekey"`
+	OneTimePrekey *OneTimePrekey `json:"one_time_prekey,omitempty"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+type PrekeyCountResponse struct {
+	OneTimePrekeys int  `json:"one_time_prekeys"`
+	Replenish      bool `json:"replenish"` // Running low, upload more
+}
@@ -0,0 +1,271 @@
+// internal/keys/service.go
+package keys
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/atharva-navani16/chat-app.git/internal/shared/ratelimit"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	curve25519KeySize     = 32
+	maxOneTimePrekeys     = 200 // stored per user
+	maxPrekeysPerUpload   = 100
+	bundleFetchLimit      = 20 // per requester and target
+	bundleFetchWindow     = time.Hour
+	bundleFetchKeyPrefix  = "keys:bundle_fetch:" // + requester:target
+	lowPrekeyWarningCount = 10
+)
+
+var (
+	ErrInvalidIdentityKey = errors.New("identity key must be a 32-byte Ed25519 public key")
+	ErrInvalidPrekey      = errors.New("prekeys must be 32-byte X25519 public keys")
+	ErrInvalidSignature   = errors.New("signed prekey signature does not verify against the identity key")
+	ErrTooManyPrekeys     = errors.New("too many one-time prekeys")
+	ErrDuplicatePrekeyID  = errors.New("one-time prekey ID already in use")
+	ErrIdentityKeyMissing = errors.New("upload an identity key and signed prekey first")
+	ErrKeyBundleNotFound  = errors.New("user has not published a key bundle")
+)
+
+type KeyService struct {
+	db      *sql.DB
+	limiter *ratelimit.Limiter
+}
+
+func NewKeyService(db *sql.DB, rdb *redis.Client) *KeyService {
+	return &KeyService{
+		db:      db,
+		limiter: ratelimit.NewLimiter(rdb),
+	}
+}
+
+// UploadKeys publishes the user's identity key and signed prekey. The
+// signature must be an Ed25519 signature by the identity key over the raw
+// signed prekey bytes. Changing the identity key discards one-time prekeys
+// generated for the old identity.
+func (s *KeyService) UploadKeys(userID uuid.UUID, req *UploadKeysRequest) error {
+	if len(req.IdentityKey) != ed25519.PublicKeySize {
+		return ErrInvalidIdentityKey
+	}
+	if len(req.SignedPrekey.PublicKey) != curve25519KeySize {
+		return ErrInvalidPrekey
+	}
+	if !ed25519.Verify(ed25519.PublicKey(req.IdentityKey), req.SignedPrekey.PublicKey, req.SignedPrekey.Signature) {
+		return ErrInvalidSignature
+	}
+	if err := validateOneTimePrekeys(req.OneTimePrekeys); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var currentIdentityKey []byte
+	err = tx.QueryRow(`SELECT public_key FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&currentIdentityKey)
+	if err != nil {
+		return err
+	}
+
+	if currentIdentityKey != nil && !bytes.Equal(currentIdentityKey, req.IdentityKey) {
+		if _, err := tx.Exec(`DELETE FROM one_time_prekeys WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE users SET
+			public_key = $2, signed_prekey = $3, prekey_signature = $4, signed_prekey_id = $5,
+			keys_updated_at = NOW(), updated_at = NOW()
+		WHERE id = $1`
+	_, err = tx.Exec(query, userID, req.IdentityKey, req.SignedPrekey.PublicKey,
+		req.SignedPrekey.Signature, req.SignedPrekey.KeyID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.insertOneTimePrekeys(tx, userID, req.OneTimePrekeys); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UploadOneTimePrekeys adds a batch of one-time prekeys
+func (s *KeyService) UploadOneTimePrekeys(userID uuid.UUID, prekeys []OneTimePrekey) (int, error) {
+	if err := validateOneTimePrekeys(prekeys); err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Lock the user row so concurrent uploads cannot exceed the cap
+	var identityKey []byte
+	err = tx.QueryRow(`SELECT public_key FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&identityKey)
+	if err != nil {
+		return 0, err
+	}
+	if identityKey == nil {
+		return 0, ErrIdentityKeyMissing
+	}
+
+	if err := s.insertOneTimePrekeys(tx, userID, prekeys); err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM one_time_prekeys WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountOneTimePrekeys returns how many one-time prekeys the user has left
+func (s *KeyService) CountOneTimePrekeys(userID uuid.UUID) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM one_time_prekeys WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// GetPrekeyBundle returns the target's key bundle and consumes one of their
+// one-time prekeys, so each prekey is handed to exactly one initiator
+func (s *KeyService) GetPrekeyBundle(requesterID, targetID uuid.UUID) (*PrekeyBundle, error) {
+	// Limit how fast one requester can drain someone's one-time prekeys
+	result, err := s.limiter.Allow(context.Background(),
+		bundleFetchKeyPrefix+requesterID.String()+":"+targetID.String(), bundleFetchLimit, bundleFetchWindow)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Allowed {
+		return nil, &auth.RateLimitError{Reason: "too many key bundle requests", RetryAfter: result.RetryAfter}
+	}
+
+	bundle := &PrekeyBundle{UserID: targetID}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// FOR SHARE keeps the identity and signed prekey from being replaced
+	// while the one-time prekey is claimed, so the bundle stays consistent
+	var signedPrekeyID sql.NullInt64
+	var updatedAt sql.NullTime
+	query := `
+		SELECT public_key, signed_prekey, prekey_signature, signed_prekey_id, keys_updated_at
+		FROM users WHERE id = $1 AND status = 'active'
+		FOR SHARE`
+	err = tx.QueryRow(query, targetID).Scan(
+		&bundle.IdentityKey, &bundle.SignedPrekey.PublicKey, &bundle.SignedPrekey.Signature,
+		&signedPrekeyID, &updatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrKeyBundleNotFound
+		}
+		return nil, err
+	}
+	if bundle.IdentityKey == nil || bundle.SignedPrekey.PublicKey == nil {
+		return nil, ErrKeyBundleNotFound
+	}
+	bundle.SignedPrekey.KeyID = int(signedPrekeyID.Int64)
+	bundle.UpdatedAt = updatedAt.Time
+
+	// Claim the oldest prekey in the same statement that deletes it; SKIP
+	// LOCKED lets concurrent fetches take different keys
+	var prekey OneTimePrekey
+	query = `
+		DELETE FROM one_time_prekeys
+		WHERE id = (
+			SELECT id FROM one_time_prekeys
+			WHERE user_id = $1
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING key_id, public_key`
+	err = tx.QueryRow(query, targetID).Scan(&prekey.KeyID, &prekey.PublicKey)
+	switch {
+	case err == nil:
+		bundle.OneTimePrekey = &prekey
+	case err == sql.ErrNoRows:
+		// Out of one-time prekeys; X3DH proceeds with the signed prekey only
+	default:
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// insertOneTimePrekeys stores prekeys, enforcing the per-user cap
+func (s *KeyService) insertOneTimePrekeys(tx *sql.Tx, userID uuid.UUID, prekeys []OneTimePrekey) error {
+	if len(prekeys) == 0 {
+		return nil
+	}
+
+	var existing int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM one_time_prekeys WHERE user_id = $1`, userID).Scan(&existing); err != nil {
+		return err
+	}
+	if existing+len(prekeys) > maxOneTimePrekeys {
+		return fmt.Errorf("%w: at most %d may be stored", ErrTooManyPrekeys, maxOneTimePrekeys)
+	}
+
+	query := `INSERT INTO one_time_prekeys (user_id, key_id, public_key) VALUES ($1, $2, $3)`
+	for _, prekey := range prekeys {
+		if _, err := tx.Exec(query, userID, prekey.KeyID, prekey.PublicKey); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				return ErrDuplicatePrekeyID
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateOneTimePrekeys checks key sizes and IDs within a batch
+func validateOneTimePrekeys(prekeys []OneTimePrekey) error {
+	if len(prekeys) > maxPrekeysPerUpload {
+		return fmt.Errorf("%w: at most %d per upload", ErrTooManyPrekeys, maxPrekeysPerUpload)
+	}
+
+	seen := make(map[int]bool, len(prekeys))
+	for _, prekey := range prekeys {
+		if len(prekey.PublicKey) != curve25519KeySize {
+			return ErrInvalidPrekey
+		}
+		if seen[prekey.KeyID] {
+			return ErrDuplicatePrekeyID
+		}
+		seen[prekey.KeyID] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Limiter implements sliding-window rate limits on Redis sorted sets.
+// Each key holds one member per hit, scored by its time in milliseconds.
+type Limiter struct {
+	rdb *redis.Client
+}
+
+// Result describes the state of a window after a hit
+type Result struct {
+	Allowed    bool
+	Count      int64
+	Granted    int64 // hits recorded by AllowUpTo
+	RetryAfter time.Duration
+}
+
+// allowScript drops expired hits, then records a new hit only if the window
+// has room. Returns {allowed, count, retry_after_ms}.
+var allowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	redis.call('PEXPIRE', key, window)
+	return {1, count + 1, 0}
+end
+
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local retry = window
+if oldest[2] then
+	retry = tonumber(oldest[2]) + window - now
+end
+return {0, count, retry}
+`)
+
+// allowUpToScript is allowScript for a batch: it records as many of n hits
+// as fit in the window. Returns {granted, count, retry_after_ms}, with
+// retry_after_ms set when part of the batch did not fit.
+var allowUpToScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local granted = math.max(math.min(n, limit - count), 0)
+for i = 1, granted do
+	redis.call('ZADD', key, now, ARGV[5] .. ':' .. i)
+end
+if granted > 0 then
+	redis.call('PEXPIRE', key, window)
+end
+count = count + granted
+if granted == n then
+	return {granted, count, 0}
+end
+
+local retry = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	retry = tonumber(oldest[2]) + window - now
+end
+return {granted, count, retry}
+`)
+
+// recordScript drops expired hits and always records a new one.
+// Returns the number of hits in the window.
+var recordScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+redis.call('ZADD', key, now, ARGV[3])
+redis.call('PEXPIRE', key, window)
+return redis.call('ZCARD', key)
+`)
+
+// NewLimiter creates a limiter backed by Redis
+func NewLimiter(rdb *redis.Client) *Limiter {
+	return &Limiter{rdb: rdb}
+}
+
+// Allow records a hit for key unless limit hits already happened within window
+func (l *Limiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (*Result, error) {
+	now := time.Now().UnixMilli()
+
+	values, err := allowScript.Run(ctx, l.rdb, []string{key},
+		now, window.Milliseconds(), limit, hitMember(now)).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("rate limit check failed: %v", err)
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("rate limit check failed: unexpected reply")
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Count:      values[1],
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+// AllowUpTo records as many of n hits for key as fit within limit for
+// window. Allowed is only set when the whole batch fit; Granted says how
+// many hits were recorded.
+func (l *Limiter) AllowUpTo(ctx context.Context, key string, n, limit int, window time.Duration) (*Result, error) {
+	now := time.Now().UnixMilli()
+	if n <= 0 {
+		return &Result{Allowed: true}, nil
+	}
+
+	values, err := allowUpToScript.Run(ctx, l.rdb, []string{key},
+		now, window.Milliseconds(), limit, n, hitMember(now)).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("rate limit check failed: %v", err)
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("rate limit check failed: unexpected reply")
+	}
+
+	return &Result{
+		Allowed:    values[0] == int64(n),
+		Count:      values[1],
+		Granted:    values[0],
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+// Record adds a hit for key and returns how many hits fall within window
+func (l *Limiter) Record(ctx context.Context, key string, window time.Duration) (int64, error) {
+	now := time.Now().UnixMilli()
+
+	count, err := recordScript.Run(ctx, l.rdb, []string{key},
+		now, window.Milliseconds(), hitMember(now)).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("rate limit record failed: %v", err)
+	}
+	return count, nil
+}
+
+// Reset clears all hits for key
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.rdb.Del(ctx, key).Err()
+}
+
+// hitMember makes sorted set members unique when hits share a millisecond
+func hitMember(now int64) string {
+	return fmt.Sprintf("%d-%s", now, uuid.NewString())
+}
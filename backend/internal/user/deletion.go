@@ -0,0 +1,199 @@
+// internal/user/deletion.go
+package user
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// deletedAccountName replaces the name of anonymised accounts, which stay on
+// as the sender of their old group messages
+const deletedAccountName = "Deleted Account"
+
+// deletionDue matches accounts whose deletion grace period has ended
+const deletionDue = `status = 'deactivated' AND deletion_scheduled_at <= NOW()`
+
+// RunDeletionSweeper anonymises accounts whose deletion grace period has
+// ended and schedules deletion of accounts that have been inactive for too
+// long, checking every interval until ctx is cancelled. With
+// inactivityDryRun set, inactive accounts are only reported.
+func (s *UserService) RunDeletionSweeper(ctx context.Context, interval time.Duration, inactivityDryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.PurgeScheduledDeletions()
+		if err != nil {
+			log.Printf("❌ Account deletion sweep failed: %v", err)
+		} else if deleted > 0 {
+			log.Printf("🗑️ Deleted %d account(s) after their grace period", deleted)
+		}
+
+		report, err := s.PurgeInactiveAccounts(inactivityDryRun)
+		if err != nil {
+			log.Printf("❌ Inactive account sweep failed: %v", err)
+		} else {
+			logInactivityReport(report)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeScheduledDeletions deletes every deactivated account whose grace
+// period has ended and returns how many were deleted
+func (s *UserService) PurgeScheduledDeletions() (int, error) {
+	query := `
+		SELECT id FROM users
+		WHERE ` + deletionDue + `
+		ORDER BY deletion_scheduled_at
+		LIMIT 100`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return 0, err
+	}
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, userID := range userIDs {
+		if err := s.deleteAccount(userID, deletionDue); err != nil {
+			log.Printf("❌ Failed to delete account %s: %v", userID, err)
+			continue
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// deleteAccount anonymises a user. The row is kept, renamed "Deleted
+// Account", so messages the user sent to groups keep a sender; everything
+// else that identifies the user is removed along with their sessions,
+// contacts, keys and files. due is re-checked with the row locked, so
+// accounts that became active again since the sweep started are left alone.
+func (s *UserService) deleteAccount(userID uuid.UUID, due string) error {
+	deleted := false
+	err := s.withTx(func(tx *sql.Tx) error {
+		var isDue bool
+		query := `SELECT ` + due + ` FROM users WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRow(query, userID).Scan(&isDue); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if !isDue {
+			return nil
+		}
+
+		// Nobody can take over the username straight away
+		query = `
+			INSERT INTO username_history (user_id, username, changed_at, reserved_until)
+			SELECT id, username, NOW(), $2 FROM users WHERE id = $1 AND username IS NOT NULL`
+		if _, err := tx.Exec(query, userID, time.Now().Add(usernameReservationPeriod)); err != nil {
+			return err
+		}
+
+		query = `
+			UPDATE users
+			SET status = 'deleted', deleted_at = NOW(), deletion_scheduled_at = NULL,
+			    first_name = $2, last_name = NULL, username = NULL, phone_number = NULL,
+			    bio = NULL, profile_photo_id = NULL, password_hash = NULL,
+			    public_key = NULL, signed_prekey = NULL, prekey_signature = NULL,
+			    signed_prekey_id = NULL, keys_updated_at = NULL, phone_verified_at = NULL,
+			    is_public = false, allow_phone_discovery = false,
+			    is_online = false, last_seen = NULL, updated_at = NOW()
+			WHERE id = $1`
+		if _, err := tx.Exec(query, userID, deletedAccountName); err != nil {
+			return err
+		}
+
+		cleanup := []string{
+			`DELETE FROM profile_photos WHERE user_id = $1`,
+			`DELETE FROM one_time_prekeys WHERE user_id = $1`,
+			`DELETE FROM user_contacts WHERE user_id = $1 OR contact_user_id = $1`,
+			`DELETE FROM contact_import_numbers WHERE user_id = $1`,
+			`DELETE FROM user_recovery_codes WHERE user_id = $1`,
+			`DELETE FROM user_totp WHERE user_id = $1`,
+			`UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`,
+			`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`,
+			`UPDATE chat_members SET status = 'left', left_at = NOW() WHERE user_id = $1 AND status = 'active'`,
+		}
+		for _, query := range cleanup {
+			if _, err := tx.Exec(query, userID); err != nil {
+				return err
+			}
+		}
+
+		deleted = true
+		return nil
+	})
+	if err != nil || !deleted {
+		return err
+	}
+
+	s.wsHub.DisconnectUser(userID)
+
+	// Files attached to messages stay with the history they belong to
+	fileIDs, err := s.getUnreferencedFiles(userID)
+	if err != nil {
+		log.Printf("❌ Failed to list files of deleted account %s: %v", userID, err)
+	} else {
+		s.deleteStoredFiles(userID, fileIDs...)
+	}
+
+	log.Printf("🗑️ Account %s deleted", userID)
+	return nil
+}
+
+// getUnreferencedFiles lists files the user uploaded that no message or
+// chat photo uses, directly or as a thumbnail. Files with thumbnails come
+// first so the thumbnails are removed after them.
+func (s *UserService) getUnreferencedFiles(userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT f.id FROM files f
+		WHERE f.uploaded_by = $1
+		  AND NOT EXISTS(SELECT 1 FROM messages m WHERE m.file_id = f.id)
+		  AND NOT EXISTS(SELECT 1 FROM chats c WHERE c.photo_id = f.id)
+		  AND NOT EXISTS(SELECT 1 FROM files t JOIN messages m ON m.file_id = t.id WHERE t.thumbnail_file_id = f.id)
+		ORDER BY f.thumbnail_file_id IS NULL`
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fileIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		fileIDs = append(fileIDs, id)
+	}
+
+	return fileIDs, rows.Err()
+}
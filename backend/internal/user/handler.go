@@ -0,0 +1,303 @@
+// internal/user/handler.go
+package user
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/atharva-navani16/chat-app.git/internal/file"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type UserHandler struct {
+	userService *UserService
+}
+
+func NewUserHandler(userService *UserService) *UserHandler {
+	return &UserHandler{
+		userService: userService,
+	}
+}
+
+// UpdateProfile updates the current user's profile
+// PUT /api/v1/users/me
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updated, err := h.userService.UpdateProfile(user.Id, &req)
+	if err != nil {
+		var validationErr *ValidationError
+		var rateLimitErr *auth.RateLimitError
+		switch {
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "field": validationErr.Field})
+		case errors.As(err, &rateLimitErr):
+			retryAfter := int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Username was changed recently, please try again later",
+				"code":        "USERNAME_CHANGE_COOLDOWN",
+				"retry_after": retryAfter,
+			})
+		case errors.Is(err, ErrInvalidUsername), errors.Is(err, ErrEmptyUpdate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrUsernameTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, auth.ErrUsernameReserved):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "USERNAME_RESERVED"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"data":    updated,
+	})
+}
+
+// GetPublicProfile returns a public user profile, filtered by the owner's
+// privacy settings for the (optional) current user
+// GET /api/v1/public/users/:username
+func (h *UserHandler) GetPublicProfile(c *gin.Context) {
+	var viewerID uuid.UUID
+	if viewer, ok := auth.GetCurrentUser(c); ok {
+		viewerID = viewer.Id
+	}
+
+	profile, err := h.userService.GetPublicProfile(c.Param("username"), viewerID)
+	if err != nil {
+		var movedErr *UsernameMovedError
+		if errors.As(err, &movedErr) {
+			c.Redirect(http.StatusTemporaryRedirect, "/api/v1/public/users/"+url.PathEscape(movedErr.Username))
+			return
+		}
+		if errors.Is(err, ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Public profile retrieved",
+		"data":    profile,
+	})
+}
+
+// GetUserStatus returns a user's online status and last seen time
+// GET /api/v1/users/:user_id/status
+func (h *UserHandler) GetUserStatus(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	status, err := h.userService.GetUserStatus(userID, user.Id)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User status retrieved",
+		"data":    status,
+	})
+}
+
+// GetAccountTTL returns how long the current user's account may stay inactive
+// GET /api/v1/users/me/account-ttl
+func (h *UserHandler) GetAccountTTL(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	ttl, err := h.userService.GetAccountTTL(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get account self-destruct setting"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account self-destruct setting retrieved",
+		"data":    ttl,
+	})
+}
+
+// SetAccountTTL sets how long the current user's account may stay inactive
+// before it is deleted
+// PUT /api/v1/users/me/account-ttl
+func (h *UserHandler) SetAccountTTL(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	var req SetAccountTTLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ttl, err := h.userService.SetAccountTTL(user.Id, req.Months)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAccountTTL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account self-destruct setting"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account self-destruct setting updated",
+		"data":    ttl,
+	})
+}
+
+// UploadProfilePhoto sets a new profile photo for the current user
+// POST /api/v1/users/me/photos
+func (h *UserHandler) UploadProfilePhoto(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, file.MaxProfilePhotoSize+1024*1024)
+	fileHeader, err := c.FormFile("photo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "No photo provided",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	photo, err := h.userService.UploadProfilePhoto(user.Id, fileHeader)
+	if err != nil {
+		respondPhotoError(c, err, "Failed to upload profile photo")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Profile photo updated successfully",
+		"data":    photo,
+	})
+}
+
+// ListProfilePhotos returns the current user's profile photo history
+// GET /api/v1/users/me/photos
+func (h *UserHandler) ListProfilePhotos(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	photos, err := h.userService.ListProfilePhotos(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get profile photos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile photos retrieved successfully",
+		"data":    photos,
+	})
+}
+
+// SetCurrentProfilePhoto makes a photo from the history current
+// PUT /api/v1/users/me/photos/:photo_id/current
+func (h *UserHandler) SetCurrentProfilePhoto(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	photoID, err := uuid.Parse(c.Param("photo_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo ID"})
+		return
+	}
+
+	if err := h.userService.SetCurrentProfilePhoto(user.Id, photoID); err != nil {
+		respondPhotoError(c, err, "Failed to set profile photo")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile photo updated successfully",
+	})
+}
+
+// DeleteProfilePhoto removes a photo from the history
+// DELETE /api/v1/users/me/photos/:photo_id
+func (h *UserHandler) DeleteProfilePhoto(c *gin.Context) {
+	user, exists := auth.RequireUser(c)
+	if !exists {
+		return
+	}
+
+	photoID, err := uuid.Parse(c.Param("photo_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo ID"})
+		return
+	}
+
+	if err := h.userService.DeleteProfilePhoto(user.Id, photoID); err != nil {
+		respondPhotoError(c, err, "Failed to delete profile photo")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile photo deleted successfully",
+	})
+}
+
+// respondPhotoError maps profile photo errors to HTTP responses
+func respondPhotoError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, file.ErrInvalidProfilePhoto):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, file.ErrProfilePhotoTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTooManyProfilePhotos):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrProfilePhotoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,148 @@
+// internal/user/inactivity.go
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/google/uuid"
+)
+
+// inactivityDue matches active accounts that have not been seen for longer
+// than their self-destruct period. Accounts never seen count from signup.
+const inactivityDue = `status = 'active'
+	AND COALESCE(last_seen, created_at) < NOW() - make_interval(months => account_ttl_months)`
+
+var ErrInvalidAccountTTL = errors.New("account_ttl_months must be 1, 3, 6 or 12")
+
+// validAccountTTLs mirrors the account_ttl_months constraint on users
+var validAccountTTLs = map[int]bool{1: true, 3: true, 6: true, 12: true}
+
+// GetAccountTTL returns the user's inactivity self-destruct period
+func (s *UserService) GetAccountTTL(userID uuid.UUID) (*AccountTTL, error) {
+	var ttl AccountTTL
+	query := `SELECT account_ttl_months FROM users WHERE id = $1`
+	if err := s.db.QueryRow(query, userID).Scan(&ttl.Months); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &ttl, nil
+}
+
+// SetAccountTTL changes how long the account may stay inactive before it is
+// deleted
+func (s *UserService) SetAccountTTL(userID uuid.UUID, months int) (*AccountTTL, error) {
+	if !validAccountTTLs[months] {
+		return nil, ErrInvalidAccountTTL
+	}
+
+	var ttl AccountTTL
+	query := `
+		UPDATE users SET account_ttl_months = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING account_ttl_months`
+	if err := s.db.QueryRow(query, userID, months).Scan(&ttl.Months); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &ttl, nil
+}
+
+// PurgeInactiveAccounts schedules deletion of accounts that have been
+// inactive for longer than their self-destruct period. They go through the
+// same grace period as a requested deletion, so signing in again before it
+// ends keeps the account. In dry-run mode nothing is changed and the report
+// lists the accounts that would have been scheduled.
+func (s *UserService) PurgeInactiveAccounts(dryRun bool) (*InactivityReport, error) {
+	query := `
+		SELECT id, COALESCE(last_seen, created_at), account_ttl_months
+		FROM users
+		WHERE ` + inactivityDue + `
+		ORDER BY last_seen NULLS FIRST
+		LIMIT 100`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &InactivityReport{DryRun: dryRun, Accounts: []InactiveAccount{}}
+	for rows.Next() {
+		var account InactiveAccount
+		if err := rows.Scan(&account.UserID, &account.LastSeen, &account.AccountTTLMonths); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		// A long-lived connection on this server counts as activity
+		if s.wsHub.IsUserOnline(account.UserID) {
+			continue
+		}
+		report.Accounts = append(report.Accounts, account)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	for _, account := range report.Accounts {
+		scheduled, err := s.scheduleInactiveDeletion(account.UserID)
+		if err != nil {
+			log.Printf("❌ Failed to schedule deletion of inactive account %s: %v", account.UserID, err)
+			continue
+		}
+		if scheduled {
+			report.Scheduled++
+		}
+	}
+
+	return report, nil
+}
+
+// scheduleInactiveDeletion deactivates an inactive account so that it is
+// anonymised once the grace period ends. inactivityDue is re-checked in the
+// update, so accounts that became active since the sweep started are left
+// alone.
+func (s *UserService) scheduleInactiveDeletion(userID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE users
+		SET status = 'deactivated', deletion_scheduled_at = $2, updated_at = NOW()
+		WHERE id = $1 AND ` + inactivityDue
+	result, err := s.db.Exec(query, userID, time.Now().Add(auth.AccountDeletionGracePeriod))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// logInactivityReport writes a sweep report to the log
+func logInactivityReport(report *InactivityReport) {
+	if !report.DryRun {
+		if report.Scheduled > 0 {
+			log.Printf("🗑️ Scheduled deletion of %d inactive account(s)", report.Scheduled)
+		}
+		return
+	}
+
+	if len(report.Accounts) == 0 {
+		return
+	}
+	log.Printf("🧪 Dry run: %d inactive account(s) would be scheduled for deletion", len(report.Accounts))
+	for _, account := range report.Accounts {
+		log.Printf("   %s last seen %s (self-destruct after %d month(s))",
+			account.UserID, account.LastSeen.Format(time.RFC3339), account.AccountTTLMonths)
+	}
+}
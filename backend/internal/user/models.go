@@ -0,0 +1,75 @@
+package user
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UpdateProfileRequest is a partial profile update; omitted fields are left unchanged
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+	Bio       *string `json:"bio,omitempty"`
+	Username  *string `json:"username,omitempty"`
+}
+
+// PublicProfile is a user's profile as seen by another user. Fields hidden
+// by the owner's privacy settings are omitted.
+type PublicProfile struct {
+	ID             uuid.UUID  `json:"id"`
+	Username       string     `json:"username"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name,omitempty"`
+	Bio            string     `json:"bio,omitempty"`
+	ProfilePhotoID *uuid.UUID `json:"profile_photo_id,omitempty"`
+	AvatarURL      string     `json:"avatar_url,omitempty"`
+	PhoneNumber    string     `json:"phone_number,omitempty"`
+	IsOnline       *bool      `json:"is_online,omitempty"`
+	LastSeen       *time.Time `json:"last_seen,omitempty"`
+	LastSeenStatus string     `json:"last_seen_status"`
+	IsContact      bool       `json:"is_contact"`
+	JoinedAt       time.Time  `json:"joined_at"`
+}
+
+// UserStatus is a user's presence as seen by another user. IsOnline and
+// LastSeen are only set when the owner's last_seen_privacy allows it;
+// otherwise Status is one of the coarse values such as "recently".
+type UserStatus struct {
+	UserID   uuid.UUID  `json:"user_id"`
+	Status   string     `json:"status"`
+	IsOnline *bool      `json:"is_online,omitempty"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// ProfilePhoto is one entry in a user's profile photo history
+type ProfilePhoto struct {
+	ID        uuid.UUID `json:"id"`
+	SmallURL  string    `json:"small_url"` // 160px square
+	BigURL    string    `json:"big_url"`   // 640px square
+	IsCurrent bool      `json:"is_current"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountTTL is how many months of inactivity an account survives
+type AccountTTL struct {
+	Months int `json:"account_ttl_months"`
+}
+
+type SetAccountTTLRequest struct {
+	Months int `json:"account_ttl_months" binding:"required"`
+}
+
+// InactiveAccount is an account due for deletion after inactivity
+type InactiveAccount struct {
+	UserID           uuid.UUID `json:"user_id"`
+	LastSeen         time.Time `json:"last_seen"`
+	AccountTTLMonths int       `json:"account_ttl_months"`
+}
+
+// InactivityReport summarises one inactivity sweep
+type InactivityReport struct {
+	DryRun    bool              `json:"dry_run"`
+	Accounts  []InactiveAccount `json:"accounts"`
+	Scheduled int               `json:"scheduled"`
+}
@@ -0,0 +1,206 @@
+// internal/user/photos.go
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"mime/multipart"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/google/uuid"
+)
+
+const maxProfilePhotos = 100 // history kept per user
+
+var (
+	ErrProfilePhotoNotFound = errors.New("profile photo not found")
+	ErrTooManyProfilePhotos = errors.New("too many profile photos, delete some first")
+)
+
+// UploadProfilePhoto stores a new profile photo and makes it current
+func (s *UserService) UploadProfilePhoto(userID uuid.UUID, fileHeader *multipart.FileHeader) (*ProfilePhoto, error) {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM profile_photos WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= maxProfilePhotos {
+		return nil, ErrTooManyProfilePhotos
+	}
+
+	files, err := s.fileService.UploadProfilePhoto(fileHeader, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	photo := &ProfilePhoto{
+		SmallURL:  files.Small.CDNUrl,
+		BigURL:    files.Big.CDNUrl,
+		IsCurrent: true,
+	}
+
+	err = s.withTx(func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO profile_photos (user_id, small_file_id, big_file_id)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at`
+		if err := tx.QueryRow(query, userID, files.Small.ID, files.Big.ID).Scan(&photo.ID, &photo.CreatedAt); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`UPDATE users SET profile_photo_id = $2, updated_at = NOW() WHERE id = $1`, userID, photo.ID)
+		return err
+	})
+	if err != nil {
+		s.deleteStoredFiles(userID, files.Small.ID, files.Big.ID)
+		return nil, err
+	}
+
+	s.notifyPhotoChanged(userID)
+
+	return photo, nil
+}
+
+// ListProfilePhotos returns the user's profile photo history, newest first
+func (s *UserService) ListProfilePhotos(userID uuid.UUID) ([]ProfilePhoto, error) {
+	query := `
+		SELECT pp.id, small.cdn_url, big.cdn_url, pp.id = u.profile_photo_id, pp.created_at
+		FROM profile_photos pp
+		JOIN users u ON u.id = pp.user_id
+		JOIN files small ON small.id = pp.small_file_id
+		JOIN files big ON big.id = pp.big_file_id
+		WHERE pp.user_id = $1
+		ORDER BY pp.created_at DESC`
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	photos := []ProfilePhoto{}
+	for rows.Next() {
+		var photo ProfilePhoto
+		var isCurrent sql.NullBool
+		if err := rows.Scan(&photo.ID, &photo.SmallURL, &photo.BigURL, &isCurrent, &photo.CreatedAt); err != nil {
+			return nil, err
+		}
+		photo.IsCurrent = isCurrent.Bool
+		photos = append(photos, photo)
+	}
+
+	return photos, rows.Err()
+}
+
+// SetCurrentProfilePhoto makes an earlier photo from the history current again
+func (s *UserService) SetCurrentProfilePhoto(userID, photoID uuid.UUID) error {
+	query := `
+		UPDATE users SET profile_photo_id = $2, updated_at = NOW()
+		WHERE id = $1 AND EXISTS(SELECT 1 FROM profile_photos WHERE id = $2 AND user_id = $1)`
+	result, err := s.db.Exec(query, userID, photoID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrProfilePhotoNotFound
+	}
+
+	s.notifyPhotoChanged(userID)
+	return nil
+}
+
+// DeleteProfilePhoto removes a photo from the history along with its files.
+// Deleting the current photo falls back to the next most recent one.
+func (s *UserService) DeleteProfilePhoto(userID, photoID uuid.UUID) error {
+	var smallFileID, bigFileID uuid.UUID
+	var wasCurrent bool
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		err := tx.QueryRow(`
+			SELECT u.profile_photo_id IS NOT DISTINCT FROM $2
+			FROM users u WHERE u.id = $1 FOR UPDATE`, userID, photoID).Scan(&wasCurrent)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(`
+			DELETE FROM profile_photos WHERE id = $2 AND user_id = $1
+			RETURNING small_file_id, big_file_id`, userID, photoID).Scan(&smallFileID, &bigFileID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrProfilePhotoNotFound
+			}
+			return err
+		}
+
+		if !wasCurrent {
+			return nil
+		}
+
+		// ON DELETE SET NULL cleared profile_photo_id; promote the newest remaining photo
+		_, err = tx.Exec(`
+			UPDATE users SET updated_at = NOW(), profile_photo_id = (
+				SELECT id FROM profile_photos WHERE user_id = $1
+				ORDER BY created_at DESC LIMIT 1
+			)
+			WHERE id = $1`, userID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.deleteStoredFiles(userID, smallFileID, bigFileID)
+
+	if wasCurrent {
+		s.notifyPhotoChanged(userID)
+	}
+	return nil
+}
+
+// withTx runs fn in a transaction, committing if it succeeds
+func (s *UserService) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteStoredFiles removes files that are no longer referenced
+func (s *UserService) deleteStoredFiles(userID uuid.UUID, fileIDs ...uuid.UUID) {
+	for _, fileID := range fileIDs {
+		if err := s.fileService.DeleteFile(fileID, userID); err != nil {
+			log.Printf("❌ Failed to delete file %s: %v", fileID, err)
+		}
+	}
+}
+
+// notifyPhotoChanged sends the user's profile, with the new avatar, to chat partners
+func (s *UserService) notifyPhotoChanged(userID uuid.UUID) {
+	query := `
+		SELECT id, phone_number, username, first_name, COALESCE(last_name, ''),
+		       COALESCE(bio, ''), profile_photo_id, ` + auth.AvatarURLExpr("users") + `
+		FROM users WHERE id = $1`
+
+	var user auth.UserResponse
+	var profilePhotoID uuid.NullUUID
+	var avatarURL sql.NullString
+	err := s.db.QueryRow(query, userID).Scan(
+		&user.Id, &user.PhoneNumber, &user.Username, &user.FirstName, &user.LastName,
+		&user.Bio, &profilePhotoID, &avatarURL,
+	)
+	if err != nil {
+		log.Printf("❌ Failed to load profile for %s: %v", userID, err)
+		return
+	}
+	user.ProfilePhotoID = profilePhotoID.UUID
+	user.AvatarURL = avatarURL.String
+
+	s.notifyProfileUpdated(&user)
+}
@@ -0,0 +1,68 @@
+// internal/user/presence.go
+package user
+
+import (
+	"database/sql"
+
+	"github.com/atharva-navani16/chat-app.git/internal/chat"
+	"github.com/google/uuid"
+)
+
+// GetUserStatus returns whether a user is online and when they were last
+// seen, as precisely as their last_seen_privacy lets the viewer see
+func (s *UserService) GetUserStatus(userID uuid.UUID, viewerID uuid.UUID) (*UserStatus, error) {
+	query := `
+		SELECT u.is_online, u.last_seen, COALESCE(u.last_seen_privacy, 'everyone'),
+		       EXISTS(SELECT 1 FROM user_contacts uc
+		              WHERE uc.user_id = u.id AND uc.contact_user_id = $2
+		                AND uc.is_contact = true AND uc.is_blocked = false),
+		       EXISTS(SELECT 1 FROM user_contacts uc
+		              WHERE uc.user_id = u.id AND uc.contact_user_id = $2 AND uc.is_blocked = true)
+		FROM users u
+		WHERE u.id = $1 AND u.status = 'active'`
+
+	var isOnline sql.NullBool
+	var lastSeen sql.NullTime
+	var lastSeenPrivacy string
+	relation := viewerRelation{isSelf: userID == viewerID}
+	err := s.db.QueryRow(query, userID, viewerID).Scan(
+		&isOnline, &lastSeen, &lastSeenPrivacy, &relation.isContact, &relation.isBlocked,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	status := s.presenceFor(userID, relation, lastSeenPrivacy, isOnline.Bool, lastSeen)
+	return &status, nil
+}
+
+// presenceFor applies last_seen_privacy to a user's presence. Viewers outside
+// the allowed audience get a coarse status instead of exact times, and
+// blocked viewers always see "long ago".
+func (s *UserService) presenceFor(userID uuid.UUID, relation viewerRelation, lastSeenPrivacy string, isOnline bool, lastSeen sql.NullTime) UserStatus {
+	status := UserStatus{UserID: userID}
+
+	// The flag in the database can lag behind connections on this server
+	isOnline = isOnline || s.wsHub.IsUserOnline(userID)
+
+	switch {
+	case relation.isBlocked && !relation.isSelf:
+		status.Status = chat.PresenceLongAgo
+	case relation.allows(lastSeenPrivacy):
+		status.Status = chat.PresenceOffline
+		if isOnline {
+			status.Status = chat.PresenceOnline
+		}
+		status.IsOnline = &isOnline
+		if lastSeen.Valid {
+			status.LastSeen = &lastSeen.Time
+		}
+	default:
+		status.Status = chat.CoarsePresence(lastSeen.Time, isOnline)
+	}
+
+	return status
+}
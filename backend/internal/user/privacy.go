@@ -0,0 +1,36 @@
+// internal/user/privacy.go
+package user
+
+// Privacy setting values shared by last_seen_privacy and phone_number_privacy
+const (
+	PrivacyEveryone = "everyone"
+	PrivacyContacts = "contacts"
+	PrivacyNobody   = "nobody"
+)
+
+// viewerRelation describes who is looking at a profile
+type viewerRelation struct {
+	isSelf    bool
+	isContact bool // the profile owner has the viewer in their contacts
+	isBlocked bool // the profile owner has blocked the viewer
+}
+
+// allows reports whether a privacy setting lets the viewer see a field.
+// Unknown values are treated as the most restrictive setting.
+func (v viewerRelation) allows(setting string) bool {
+	if v.isSelf {
+		return true
+	}
+	if v.isBlocked {
+		return false
+	}
+
+	switch setting {
+	case PrivacyEveryone:
+		return true
+	case PrivacyContacts:
+		return v.isContact
+	default:
+		return false
+	}
+}
@@ -0,0 +1,312 @@
+// internal/user/service.go
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/atharva-navani16/chat-app.git/internal/chat"
+	"github.com/atharva-navani16/chat-app.git/internal/file"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxNameLength = 100 // matches VARCHAR(100)
+	maxBioLength  = 140
+)
+
+// Mirrors the valid_username constraint on users
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]{5,32}$`)
+
+var (
+	ErrInvalidUsername = errors.New("username must be 5-32 characters of letters, digits and underscores")
+	ErrUsernameTaken   = errors.New("username is already taken")
+	ErrEmptyUpdate     = errors.New("no fields to update")
+	ErrUserNotFound    = errors.New("user not found")
+)
+
+// ValidationError reports an invalid profile field
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+type UserService struct {
+	db          *sql.DB
+	wsHub       *chat.WSHub
+	fileService *file.FileService
+	usernames   *auth.UsernamePolicy
+}
+
+func NewUserService(db *sql.DB, wsHub *chat.WSHub, fileService *file.FileService, usernames *auth.UsernamePolicy) *UserService {
+	return &UserService{
+		db:          db,
+		wsHub:       wsHub,
+		fileService: fileService,
+		usernames:   usernames,
+	}
+}
+
+// UpdateProfile validates and saves profile changes, then tells everyone who
+// shares a chat with the user
+func (s *UserService) UpdateProfile(userID uuid.UUID, req *UpdateProfileRequest) (*auth.UserResponse, error) {
+	setClauses := []string{}
+	args := []interface{}{userID}
+
+	addField := func(column string, value interface{}) {
+		args = append(args, value)
+		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	if req.FirstName != nil {
+		firstName := strings.TrimSpace(*req.FirstName)
+		if firstName == "" {
+			return nil, &ValidationError{Field: "first_name", Message: "is required"}
+		}
+		if utf8.RuneCountInString(firstName) > maxNameLength {
+			return nil, &ValidationError{Field: "first_name", Message: fmt.Sprintf("must be at most %d characters", maxNameLength)}
+		}
+		addField("first_name", firstName)
+	}
+
+	if req.LastName != nil {
+		lastName := strings.TrimSpace(*req.LastName)
+		if utf8.RuneCountInString(lastName) > maxNameLength {
+			return nil, &ValidationError{Field: "last_name", Message: fmt.Sprintf("must be at most %d characters", maxNameLength)}
+		}
+		addField("last_name", lastName)
+	}
+
+	if req.Bio != nil {
+		bio := strings.TrimSpace(*req.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return nil, &ValidationError{Field: "bio", Message: fmt.Sprintf("must be at most %d characters", maxBioLength)}
+		}
+		addField("bio", bio)
+	}
+
+	// The previous username is kept in username_history when it changes
+	var oldUsername string
+	if req.Username != nil {
+		username := strings.TrimPrefix(strings.TrimSpace(*req.Username), "@")
+		if !usernamePattern.MatchString(username) {
+			return nil, ErrInvalidUsername
+		}
+
+		current, err := s.checkUsernameChange(userID, username)
+		if err != nil {
+			return nil, err
+		}
+		if current != username {
+			oldUsername = current
+			addField("username", username)
+			setClauses = append(setClauses, "username_changed_at = NOW()")
+		}
+	}
+
+	if len(setClauses) == 0 {
+		return nil, ErrEmptyUpdate
+	}
+	setClauses = append(setClauses, "updated_at = NOW()")
+
+	query := fmt.Sprintf(`
+		UPDATE users SET %s
+		WHERE id = $1
+		RETURNING id, phone_number, username, first_name, COALESCE(last_name, ''),
+		          COALESCE(bio, ''), profile_photo_id, %s`,
+		strings.Join(setClauses, ", "), auth.AvatarURLExpr("users"))
+
+	var user auth.UserResponse
+	var profilePhotoID uuid.NullUUID
+	var avatarURL sql.NullString
+	err := s.withTx(func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, args...).Scan(
+			&user.Id, &user.PhoneNumber, &user.Username, &user.FirstName, &user.LastName,
+			&user.Bio, &profilePhotoID, &avatarURL,
+		)
+		if err != nil || oldUsername == "" {
+			return err
+		}
+		return recordUsernameChange(tx, userID, oldUsername)
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch {
+			case pqErr.Code == "23505":
+				return nil, ErrUsernameTaken
+			case pqErr.Code == "23514" && pqErr.Constraint == "valid_username":
+				return nil, ErrInvalidUsername
+			}
+		}
+		return nil, err
+	}
+	user.ProfilePhotoID = profilePhotoID.UUID
+	user.AvatarURL = avatarURL.String
+
+	s.notifyProfileUpdated(&user)
+
+	return &user, nil
+}
+
+// GetPublicProfile returns the profile of a public account as the viewer may
+// see it. viewerID is uuid.Nil for anonymous viewers. Private, deleted and
+// banned accounts are reported as not found.
+func (s *UserService) GetPublicProfile(username string, viewerID uuid.UUID) (*PublicProfile, error) {
+	username = strings.TrimPrefix(username, "@")
+
+	query := `
+		SELECT u.id, u.username, u.first_name, COALESCE(u.last_name, ''), COALESCE(u.bio, ''),
+		       u.profile_photo_id, ` + auth.AvatarURLExpr("u") + `, u.phone_number, u.is_public,
+		       u.is_online, u.last_seen,
+		       COALESCE(u.last_seen_privacy, 'everyone'), COALESCE(u.phone_number_privacy, 'contacts'),
+		       u.created_at,
+		       EXISTS(SELECT 1 FROM user_contacts uc
+		              WHERE uc.user_id = u.id AND uc.contact_user_id = $2
+		                AND uc.is_contact = true AND uc.is_blocked = false),
+		       EXISTS(SELECT 1 FROM user_contacts uc
+		              WHERE uc.user_id = u.id AND uc.contact_user_id = $2 AND uc.is_blocked = true),
+		       EXISTS(SELECT 1 FROM user_contacts uc
+		              WHERE uc.user_id = $2 AND uc.contact_user_id = u.id AND uc.is_contact = true)
+		FROM users u
+		WHERE LOWER(u.username) = LOWER($1) AND u.status = 'active'`
+
+	var profile PublicProfile
+	var profilePhotoID uuid.NullUUID
+	var avatarURL sql.NullString
+	var phoneNumber string
+	var isPublic bool
+	var isOnline sql.NullBool
+	var lastSeen sql.NullTime
+	var lastSeenPrivacy, phonePrivacy string
+	var relation viewerRelation
+
+	err := s.db.QueryRow(query, username, viewerID).Scan(
+		&profile.ID, &profile.Username, &profile.FirstName, &profile.LastName, &profile.Bio,
+		&profilePhotoID, &avatarURL, &phoneNumber, &isPublic, &isOnline, &lastSeen,
+		&lastSeenPrivacy, &phonePrivacy,
+		&profile.JoinedAt,
+		&relation.isContact,
+		&relation.isBlocked,
+		&profile.IsContact,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Old links keep working while the previous username is reserved
+			current, found, err := s.findRenamedUser(username)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				return nil, &UsernameMovedError{Username: current}
+			}
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	relation.isSelf = viewerID != uuid.Nil && viewerID == profile.ID
+	if !isPublic && !relation.isSelf {
+		return nil, ErrUserNotFound
+	}
+
+	// Blocked viewers do not see the owner's photo
+	if profilePhotoID.Valid && !relation.isBlocked {
+		profile.ProfilePhotoID = &profilePhotoID.UUID
+		profile.AvatarURL = avatarURL.String
+	}
+
+	if relation.allows(phonePrivacy) {
+		profile.PhoneNumber = phoneNumber
+	}
+
+	presence := s.presenceFor(profile.ID, relation, lastSeenPrivacy, isOnline.Bool, lastSeen)
+	profile.LastSeenStatus = presence.Status
+	profile.IsOnline = presence.IsOnline
+	profile.LastSeen = presence.LastSeen
+
+	return &profile, nil
+}
+
+// isUsernameTaken checks for another account or a chat with the same
+// username, ignoring case so look-alike usernames cannot be registered.
+// Users and chats share one namespace.
+func (s *UserService) isUsernameTaken(username string, userID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `
+		SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(username) = LOWER($1) AND id != $2)
+		    OR EXISTS(SELECT 1 FROM chats WHERE LOWER(username) = LOWER($1))`
+	err := s.db.QueryRow(query, username, userID).Scan(&exists)
+	return exists, err
+}
+
+// notifyProfileUpdated pushes the new public profile to chat partners on
+// every server
+func (s *UserService) notifyProfileUpdated(user *auth.UserResponse) {
+	partners, err := s.getChatPartners(user.Id)
+	if err != nil {
+		log.Printf("❌ Failed to load chat partners for %s: %v", user.Id, err)
+		return
+	}
+	if len(partners) == 0 {
+		return
+	}
+
+	s.wsHub.PublishToUsers(partners, chat.WSMessage{
+		Type:   chat.WSProfileUpdated,
+		UserID: user.Id,
+		Content: map[string]interface{}{
+			"user_id":          user.Id,
+			"username":         user.Username,
+			"first_name":       user.FirstName,
+			"last_name":        user.LastName,
+			"bio":              user.Bio,
+			"profile_photo_id": user.ProfilePhotoID,
+			"avatar_url":       user.AvatarURL,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// getChatPartners returns every user who shares an active chat with the user
+func (s *UserService) getChatPartners(userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT DISTINCT other.user_id
+		FROM chat_members me
+		JOIN chat_members other ON other.chat_id = me.chat_id
+		JOIN chats c ON c.id = me.chat_id
+		WHERE me.user_id = $1 AND me.status = 'active'
+		  AND other.user_id != $1 AND other.status = 'active'
+		  AND c.is_active = true
+		  AND NOT EXISTS(SELECT 1 FROM user_contacts uc
+		                 WHERE uc.user_id = $1 AND uc.contact_user_id = other.user_id AND uc.is_blocked = true)`
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var partners []uuid.UUID
+	for rows.Next() {
+		var partnerID uuid.UUID
+		if err := rows.Scan(&partnerID); err != nil {
+			return nil, err
+		}
+		partners = append(partners, partnerID)
+	}
+
+	return partners, rows.Err()
+}
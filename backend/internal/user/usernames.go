@@ -0,0 +1,94 @@
+// internal/user/usernames.go
+package user
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/atharva-navani16/chat-app.git/internal/auth"
+	"github.com/google/uuid"
+)
+
+const (
+	usernameChangeCooldown    = 24 * time.Hour
+	usernameReservationPeriod = 14 * 24 * time.Hour // old usernames stay with their previous owner
+)
+
+// UsernameMovedError is returned when a profile is looked up by a username
+// its owner has recently changed
+type UsernameMovedError struct {
+	Username string // the owner's current username
+}
+
+func (e *UsernameMovedError) Error() string {
+	return "username has changed to " + e.Username
+}
+
+// checkUsernameChange returns the user's current username after checking
+// that they may switch to username: the change cooldown has passed, nobody
+// else has or holds the username, and it contains no reserved word
+func (s *UserService) checkUsernameChange(userID uuid.UUID, username string) (string, error) {
+	var current sql.NullString
+	var changedAt sql.NullTime
+	query := `SELECT username, username_changed_at FROM users WHERE id = $1`
+	if err := s.db.QueryRow(query, userID).Scan(&current, &changedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	if current.String == username {
+		return current.String, nil
+	}
+
+	if changedAt.Valid {
+		if wait := time.Until(changedAt.Time.Add(usernameChangeCooldown)); wait > 0 {
+			return "", &auth.RateLimitError{Reason: "username was changed recently", RetryAfter: wait}
+		}
+	}
+
+	taken, err := s.isUsernameTaken(username, userID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrUsernameTaken
+	}
+
+	if err := s.usernames.Check(username, userID); err != nil {
+		return "", err
+	}
+
+	return current.String, nil
+}
+
+// recordUsernameChange keeps the previous username reserved for its owner
+func recordUsernameChange(tx *sql.Tx, userID uuid.UUID, oldUsername string) error {
+	query := `
+		INSERT INTO username_history (user_id, username, changed_at, reserved_until)
+		VALUES ($1, $2, NOW(), $3)`
+	_, err := tx.Exec(query, userID, oldUsername, time.Now().Add(usernameReservationPeriod))
+	return err
+}
+
+// findRenamedUser returns the current username of the public account that
+// gave up username within the reservation period
+func (s *UserService) findRenamedUser(username string) (string, bool, error) {
+	query := `
+		SELECT u.username
+		FROM username_history h
+		JOIN users u ON u.id = h.user_id
+		WHERE LOWER(h.username) = LOWER($1) AND h.reserved_until > NOW()
+		  AND u.status = 'active' AND u.is_public = true AND u.username IS NOT NULL
+		ORDER BY h.changed_at DESC
+		LIMIT 1`
+
+	var current string
+	if err := s.db.QueryRow(query, username).Scan(&current); err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return current, true, nil
+}
@@ -0,0 +1,25 @@
+-- migrations/005_refresh_tokens.sql
+-- Refresh Token Rotation
+
+-- Opaque refresh tokens, stored hashed. Every token issued by rotating another
+-- one shares its family_id so a replayed token can revoke the whole chain.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL, -- SHA-256 hex of the raw token
+
+    -- Rotation
+    replaced_by UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP,
+
+    -- Metadata
+    created_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_active ON refresh_tokens(family_id) WHERE revoked_at IS NULL;
+
+COMMENT ON TABLE refresh_tokens IS 'Hashed refresh tokens with rotation and reuse detection';
@@ -0,0 +1,36 @@
+-- migrations/006_user_sessions.sql
+-- Per-device Login Sessions
+
+-- One row per login. The session ID is also the refresh token family ID and
+-- the "sid" claim carried by access tokens.
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Device info
+    device_name VARCHAR(100),
+    user_agent TEXT,
+    ip_address VARCHAR(45),
+
+    -- Metadata
+    created_at TIMESTAMP DEFAULT NOW(),
+    last_active_at TIMESTAMP DEFAULT NOW(),
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_active ON user_sessions(user_id, last_active_at DESC) WHERE revoked_at IS NULL;
+
+-- Backfill sessions for refresh token families issued before sessions existed
+INSERT INTO user_sessions (id, user_id, created_at, last_active_at, revoked_at)
+SELECT family_id, user_id, MIN(created_at), MAX(created_at),
+       CASE WHEN BOOL_AND(revoked_at IS NOT NULL) THEN MAX(revoked_at) END
+FROM refresh_tokens
+GROUP BY family_id, user_id
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT fk_refresh_tokens_session
+    FOREIGN KEY (family_id) REFERENCES user_sessions(id) ON DELETE CASCADE;
+
+COMMENT ON TABLE user_sessions IS 'Tracks logged-in devices for session management';
@@ -0,0 +1,8 @@
+-- migrations/007_phone_verification.sql
+-- Phone Number Verification
+
+-- Set when the user proved ownership of phone_number with an SMS code.
+-- Accounts created before verification existed stay NULL.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMP;
+
+COMMENT ON COLUMN users.phone_verified_at IS 'When the phone number was verified by SMS code';
@@ -0,0 +1,31 @@
+-- migrations/008_two_factor_auth.sql
+-- TOTP Two-Factor Authentication
+
+-- One TOTP secret per user. The row exists while enrollment is pending and
+-- is_enabled flips once the user confirms a code from their authenticator.
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL, -- base32 encoded
+    is_enabled BOOLEAN DEFAULT false,
+    last_used_step BIGINT, -- last accepted time step, prevents code replay
+
+    -- Metadata
+    created_at TIMESTAMP DEFAULT NOW(),
+    enabled_at TIMESTAMP
+);
+
+-- Single-use recovery codes, stored hashed
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE(user_id, code_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_unused ON user_recovery_codes(user_id) WHERE used_at IS NULL;
+
+COMMENT ON TABLE user_totp IS 'TOTP secrets for two-factor authentication';
+COMMENT ON TABLE user_recovery_codes IS 'Hashed single-use 2FA recovery codes';
@@ -0,0 +1,32 @@
+-- migrations/009_prekeys.sql
+-- Client-generated E2E key bundles (X3DH)
+
+-- Keys are uploaded by the client after registration, so they start empty.
+-- Keys generated by the server before this migration had no usable private
+-- half, so they are cleared and clients upload real ones.
+ALTER TABLE users
+    ALTER COLUMN public_key DROP NOT NULL,
+    ALTER COLUMN signed_prekey DROP NOT NULL,
+    ALTER COLUMN prekey_signature DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS signed_prekey_id INTEGER,
+    ADD COLUMN IF NOT EXISTS keys_updated_at TIMESTAMP;
+
+UPDATE users SET public_key = NULL, signed_prekey = NULL, prekey_signature = NULL
+WHERE keys_updated_at IS NULL;
+
+-- One-time prekeys, each handed out to at most one session initiator
+CREATE TABLE IF NOT EXISTS one_time_prekeys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key_id INTEGER NOT NULL, -- client-assigned ID, echoed back in bundles
+    public_key BYTEA NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE(user_id, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_one_time_prekeys_user ON one_time_prekeys(user_id, id);
+
+COMMENT ON COLUMN users.public_key IS 'Ed25519 identity key uploaded by the client';
+COMMENT ON COLUMN users.signed_prekey IS 'X25519 signed prekey, signed by the identity key';
+COMMENT ON TABLE one_time_prekeys IS 'Single-use X25519 prekeys consumed when a bundle is fetched';
@@ -0,0 +1,8 @@
+-- migrations/010_phone_privacy.sql
+-- Who can see a user's phone number on their profile
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS phone_number_privacy VARCHAR(20) DEFAULT 'contacts'
+        CHECK (phone_number_privacy IN ('everyone', 'contacts', 'nobody'));
+
+COMMENT ON COLUMN users.phone_number_privacy IS 'Phone number visibility: everyone, contacts, nobody';
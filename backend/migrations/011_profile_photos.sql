@@ -0,0 +1,33 @@
+-- migrations/011_profile_photos.sql
+-- Profile photo history
+
+-- Every photo a user has set, newest first. The current one is referenced
+-- by users.profile_photo_id.
+CREATE TABLE IF NOT EXISTS profile_photos (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Square avatar variants stored through the file service
+    small_file_id UUID NOT NULL REFERENCES files(id),
+    big_file_id UUID NOT NULL REFERENCES files(id),
+
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_profile_photos_user ON profile_photos(user_id, created_at DESC);
+
+-- profile_photo_id now points at a profile photo rather than a single file
+UPDATE users SET profile_photo_id = NULL
+WHERE profile_photo_id IS NOT NULL
+  AND profile_photo_id NOT IN (SELECT id FROM profile_photos);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_users_profile_photo') THEN
+        ALTER TABLE users
+            ADD CONSTRAINT fk_users_profile_photo
+            FOREIGN KEY (profile_photo_id) REFERENCES profile_photos(id) ON DELETE SET NULL;
+    END IF;
+END $$;
+
+COMMENT ON TABLE profile_photos IS 'Profile photo history with small and big avatar variants';
@@ -0,0 +1,16 @@
+-- migrations/012_user_blocks.sql
+-- Blocking users who are not saved contacts
+
+-- A user_contacts row can now record a block without a contact: is_contact
+-- is false for rows that only exist because of is_blocked
+ALTER TABLE user_contacts
+    ADD COLUMN IF NOT EXISTS is_contact BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN IF NOT EXISTS blocked_at TIMESTAMP;
+
+UPDATE user_contacts SET blocked_at = added_at WHERE is_blocked = true AND blocked_at IS NULL;
+
+-- "Who blocked this user" lookups for presence, typing and search
+CREATE INDEX IF NOT EXISTS idx_contacts_blocked_by ON user_contacts(contact_user_id) WHERE is_blocked = true;
+
+COMMENT ON COLUMN user_contacts.is_contact IS 'False when the row only records a block';
+COMMENT ON COLUMN user_contacts.blocked_at IS 'When user_id blocked contact_user_id';
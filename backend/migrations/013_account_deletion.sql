@@ -0,0 +1,17 @@
+-- migrations/013_account_deletion.sql
+-- Self-service account deletion with a grace period
+
+-- Deleted accounts keep their row as the tombstone sender of old messages,
+-- so the phone number has to be released
+ALTER TABLE users ALTER COLUMN phone_number DROP NOT NULL;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Sweeper lookup for accounts whose grace period has ended
+CREATE INDEX IF NOT EXISTS idx_users_deletion_due ON users(deletion_scheduled_at) WHERE status = 'deactivated';
+
+COMMENT ON COLUMN users.status IS 'active, deactivated (deletion pending), deleted (anonymised) or banned';
+COMMENT ON COLUMN users.deletion_scheduled_at IS 'When a deactivated account will be anonymised';
+COMMENT ON COLUMN users.deleted_at IS 'When the account was anonymised';
@@ -0,0 +1,16 @@
+-- migrations/014_account_ttl.sql
+-- Delete accounts automatically after a period of inactivity
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS account_ttl_months INTEGER NOT NULL DEFAULT 6
+        CHECK (account_ttl_months IN (1, 3, 6, 12));
+
+-- last_seen was not recorded before this migration, so existing accounts
+-- start their inactivity period now rather than from signup
+UPDATE users SET last_seen = NOW() WHERE last_seen IS NULL;
+
+-- Sweeper lookup for inactive accounts
+CREATE INDEX IF NOT EXISTS idx_users_last_seen ON users(last_seen) WHERE status = 'active';
+
+COMMENT ON COLUMN users.account_ttl_months IS 'Months of inactivity after which the account is deleted: 1, 3, 6 or 12';
+COMMENT ON COLUMN users.last_seen IS 'Last WebSocket or API activity';
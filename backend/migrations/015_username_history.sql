@@ -0,0 +1,19 @@
+-- migrations/015_username_history.sql
+-- Username changes, with old usernames held back for their previous owner
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_changed_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS username_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username VARCHAR(32) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    reserved_until TIMESTAMP NOT NULL
+);
+
+-- Reservation checks and redirects look up old usernames case-insensitively
+CREATE INDEX IF NOT EXISTS idx_username_history_username ON username_history(LOWER(username), changed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_username_history_user ON username_history(user_id, changed_at DESC);
+
+COMMENT ON COLUMN users.username_changed_at IS 'Last username change, for the change cooldown';
+COMMENT ON TABLE username_history IS 'Previous usernames; only the previous owner can claim one before reserved_until';
@@ -0,0 +1,16 @@
+-- migrations/016_user_search_trgm.sql
+-- Trigram indexes for fuzzy, ranked user search
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Both expressions must match the ones used by AuthService.SearchUsers
+CREATE INDEX IF NOT EXISTS idx_users_username_trgm
+    ON users USING gin (LOWER(username) gin_trgm_ops)
+    WHERE status = 'active';
+
+CREATE INDEX IF NOT EXISTS idx_users_full_name_trgm
+    ON users USING gin (LOWER(first_name || ' ' || COALESCE(last_name, '')) gin_trgm_ops)
+    WHERE status = 'active';
+
+COMMENT ON INDEX idx_users_username_trgm IS 'Fuzzy username search';
+COMMENT ON INDEX idx_users_full_name_trgm IS 'Fuzzy full name search';
@@ -0,0 +1,6 @@
+-- migrations/017_chat_history_clearing.sql
+-- "Delete chat for me": a member's history can be hidden from them alone
+
+ALTER TABLE chat_members ADD COLUMN IF NOT EXISTS cleared_at TIMESTAMP;
+
+COMMENT ON COLUMN chat_members.cleared_at IS 'Messages up to this time are hidden from the member';
@@ -0,0 +1,21 @@
+-- migrations/018_contact_import_numbers.sql
+-- Numbers each user has checked through phone book imports
+
+-- Unmatched numbers are not saved as contacts, so imports remember every
+-- number they have looked up (as a SHA-256 hex digest) to tell which ones
+-- are new and count against the daily quota
+CREATE TABLE IF NOT EXISTS contact_import_numbers (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    phone_hash CHAR(64) NOT NULL,
+    first_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, phone_hash)
+);
+
+-- Numbers already saved as contacts have been seen
+INSERT INTO contact_import_numbers (user_id, phone_hash)
+SELECT DISTINCT user_id, encode(sha256(convert_to(phone_number, 'UTF8')), 'hex')
+FROM user_contacts
+WHERE phone_number IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE contact_import_numbers IS 'Hashes of phone numbers a user has looked up by importing contacts';